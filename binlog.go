@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"time"
+)
+
+// Binary events log layout, all integers as varints unless noted:
+//
+//	header:  magic "BSEV", version (byte), metadata count, then key and value
+//	         strings, CRC-32 of the header so far (4 bytes, little endian)
+//	blocks:  record count, payload length, payload, CRC-32 of the payload
+//	         (4 bytes); a block with zero records ends the log
+//	record:  time delta in ms from the previous record (signed, the first of
+//	         each block from the project epoch, so a damaged block can be
+//	         skipped), event ID, competitor ID, typed params
+//
+// Strings are a length followed by the bytes.
+const (
+	BinaryLogMagic   = "BSEV"
+	BinaryLogVersion = 1
+
+	binaryBlockRecords = 256
+
+	DiagnosticChecksumMismatch DiagnosticKind = "checksum-mismatch"
+	DiagnosticMalformedRecord  DiagnosticKind = "malformed-record"
+)
+
+// IsBinaryLog reports whether the data starts like a binary events log.
+func IsBinaryLog(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, []byte(BinaryLogMagic))
+}
+
+type binaryWriter struct {
+	buf []byte
+}
+
+func metadataKeys(metadata map[string]string) []string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteMetadata writes the race metadata as "key: value" lines in key order.
+func WriteMetadata(w io.Writer, metadata map[string]string) {
+	for _, key := range metadataKeys(metadata) {
+		fmt.Fprintf(w, "%s: %s\n", key, metadata[key])
+	}
+}
+
+func (b *binaryWriter) uvarint(v uint64) { b.buf = binary.AppendUvarint(b.buf, v) }
+func (b *binaryWriter) varint(v int64)   { b.buf = binary.AppendVarint(b.buf, v) }
+func (b *binaryWriter) str(s string) {
+	b.uvarint(uint64(len(s)))
+	b.buf = append(b.buf, s...)
+}
+func (b *binaryWriter) crc(from int) {
+	b.buf = binary.LittleEndian.AppendUint32(b.buf, crc32.ChecksumIEEE(b.buf[from:]))
+}
+
+func millisSinceEpoch(t time.Time) int64 {
+	return t.Sub(projectEpoch).Milliseconds()
+}
+
+// WriteBinaryEvents writes events in the binary log format with the given
+// race metadata in the header.
+func WriteBinaryEvents(w io.Writer, events []Event, metadata map[string]string) error {
+	out := &binaryWriter{}
+	out.buf = append(out.buf, BinaryLogMagic...)
+	out.buf = append(out.buf, BinaryLogVersion)
+	keys := metadataKeys(metadata)
+	out.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		out.str(key)
+		out.str(metadata[key])
+	}
+	out.crc(0)
+	if _, err := w.Write(out.buf); err != nil {
+		return fmt.Errorf("failed to write binary log header: %w", err)
+	}
+
+	for start := 0; start < len(events); start += binaryBlockRecords {
+		end := min(start+binaryBlockRecords, len(events))
+		payload := &binaryWriter{}
+		previous := int64(0)
+		for _, event := range events[start:end] {
+			millis := millisSinceEpoch(event.Timestamp)
+			payload.varint(millis - previous)
+			previous = millis
+			payload.uvarint(uint64(event.ID))
+			payload.uvarint(uint64(event.CompetitorID))
+			encodeParams(payload, event)
+		}
+
+		block := &binaryWriter{}
+		block.uvarint(uint64(end - start))
+		block.uvarint(uint64(len(payload.buf)))
+		block.buf = append(block.buf, payload.buf...)
+		block.buf = binary.LittleEndian.AppendUint32(block.buf, crc32.ChecksumIEEE(payload.buf))
+		if _, err := w.Write(block.buf); err != nil {
+			return fmt.Errorf("failed to write binary log block: %w", err)
+		}
+	}
+	if _, err := w.Write([]byte{0}); err != nil {
+		return fmt.Errorf("failed to write binary log end: %w", err)
+	}
+	return nil
+}
+
+func encodeParams(out *binaryWriter, event Event) {
+	switch event.ID {
+	case EventStartTimeSet:
+		out.varint(millisSinceEpoch(event.ScheduledStartTime))
+	case EventOnFiringRange:
+		out.uvarint(uint64(event.FiringRange))
+	case EventTargetHit:
+		out.uvarint(uint64(event.Target))
+	case EventCannotContinue, EventJuryDisqualified:
+		out.str(event.Comment)
+	case EventRelayHandOff:
+		out.uvarint(uint64(event.NextCompetitorID))
+	case EventShotFired:
+		out.uvarint(uint64(event.Target)<<2 | uint64(event.Shot))
+	case EventTimePenalty:
+		out.varint(event.Penalty.Milliseconds())
+		out.str(event.Comment)
+	default:
+		out.str(event.ExtraParamsStr)
+	}
+}
+
+var errTruncated = errors.New("truncated record")
+
+type binaryReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *binaryReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf[r.pos:])
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *binaryReader) str() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if uint64(len(r.buf)-r.pos) < n {
+		r.err = errTruncated
+		return ""
+	}
+	s := string(r.buf[r.pos : r.pos+int(n)])
+	r.pos += int(n)
+	return s
+}
+
+func decodeParams(in *binaryReader, event *Event) {
+	switch event.ID {
+	case EventStartTimeSet:
+		event.ScheduledStartTime = projectEpoch.Add(time.Duration(in.varint()) * time.Millisecond)
+	case EventOnFiringRange:
+		event.FiringRange = int(in.uvarint())
+	case EventTargetHit:
+		event.Target = int(in.uvarint())
+	case EventCannotContinue, EventJuryDisqualified:
+		event.Comment = in.str()
+	case EventRelayHandOff:
+		event.NextCompetitorID = int(in.uvarint())
+	case EventShotFired:
+		packed := in.uvarint()
+		event.Target = int(packed >> 2)
+		event.Shot = ShotOutcome(packed & 3)
+	case EventTimePenalty:
+		event.Penalty = time.Duration(in.varint()) * time.Millisecond
+		event.Comment = in.str()
+	default:
+		event.ExtraParamsStr = in.str()
+		return
+	}
+	event.ExtraParamsStr = FormatEventParams(*event)
+}
+
+// ReadBinaryEvents decodes a binary events log. Each event's source line is
+// its record number and its offset the byte offset of its block. A block
+// whose checksum does not match is skipped and reported; a malformed or
+// truncated block ends the read.
+func ReadBinaryEvents(r io.Reader, file string) ([]Event, []Diagnostic, map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read binary events log '%s': %w", file, err)
+	}
+	if !IsBinaryLog(data) {
+		return nil, nil, nil, fmt.Errorf("'%s' is not a binary events log", file)
+	}
+	in := &binaryReader{buf: data, pos: len(BinaryLogMagic)}
+	if in.pos >= len(data) || data[in.pos] != BinaryLogVersion {
+		return nil, nil, nil, fmt.Errorf("unsupported binary events log version in '%s'", file)
+	}
+	in.pos++
+
+	metadata := make(map[string]string)
+	for count := in.uvarint(); count > 0 && in.err == nil; count-- {
+		key := in.str()
+		metadata[key] = in.str()
+	}
+	if in.err != nil || len(data)-in.pos < 4 {
+		return nil, nil, nil, fmt.Errorf("truncated binary events log header in '%s'", file)
+	}
+	if crc32.ChecksumIEEE(data[:in.pos]) != binary.LittleEndian.Uint32(data[in.pos:]) {
+		return nil, nil, nil, fmt.Errorf("binary events log header checksum mismatch in '%s'", file)
+	}
+	in.pos += 4
+
+	var events []Event
+	var diagnostics []Diagnostic
+	diagnose := func(kind DiagnosticKind, record int, offset int, format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     file,
+			Line:     record,
+			Column:   1,
+			Offset:   int64(offset),
+			Kind:     kind,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	record := 0
+	for {
+		blockOffset := in.pos
+		count := in.uvarint()
+		if in.err != nil {
+			diagnose(DiagnosticMalformedRecord, record+1, blockOffset, "binary events log ends without an end marker")
+			break
+		}
+		if count == 0 {
+			break
+		}
+		length := in.uvarint()
+		if remaining := uint64(len(data) - in.pos); in.err != nil || length > remaining || remaining-length < 4 {
+			diagnose(DiagnosticMalformedRecord, record+1, blockOffset, "truncated block of %d records", count)
+			break
+		}
+		payload := data[in.pos : in.pos+int(length)]
+		in.pos += int(length)
+		checksum := binary.LittleEndian.Uint32(data[in.pos:])
+		in.pos += 4
+		if crc32.ChecksumIEEE(payload) != checksum {
+			diagnose(DiagnosticChecksumMismatch, record+1, blockOffset, "checksum mismatch, skipping records %d-%d", record+1, record+int(count))
+			record += int(count)
+			continue
+		}
+
+		block := &binaryReader{buf: payload}
+		previous := int64(0)
+		for i := uint64(0); i < count; i++ {
+			record++
+			millis := previous + block.varint()
+			event := Event{
+				Timestamp:    projectEpoch.Add(time.Duration(millis) * time.Millisecond),
+				ID:           EventID(block.uvarint()),
+				CompetitorID: int(block.uvarint()),
+				Source:       SourcePos{File: file, Line: record, Offset: int64(blockOffset)},
+			}
+			decodeParams(block, &event)
+			if block.err != nil {
+				diagnose(DiagnosticMalformedRecord, record, blockOffset, "malformed record: %v", block.err)
+				return events, diagnostics, metadata, nil
+			}
+			previous = millis
+			event.Line = FormatEventLine(event)
+			events = append(events, event)
+		}
+	}
+	return events, diagnostics, metadata, nil
+}
+
+// newSniffingReader wraps r so its first bytes can be inspected without
+// consuming them.
+func newSniffingReader(r io.Reader) (*bufio.Reader, bool) {
+	reader := bufio.NewReader(r)
+	prefix, _ := reader.Peek(len(BinaryLogMagic))
+	return reader, IsBinaryLog(prefix)
+}
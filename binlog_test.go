@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestEvents(t *testing.T, events []Event) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteEvents(&buf, events); err != nil {
+		t.Fatalf("WriteEvents() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "events")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write events: %v", err)
+	}
+	return path
+}
+
+func TestBinaryEvents_RoundTrip(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Laps = 2
+	cfg.FiringLines = 2
+	events := GenerateRace(cfg, 40, DefaultRaceProfile(), 42)
+	events = append(events,
+		Event{Timestamp: testTime(11, 0, 0, 0), ID: EventShotFired, CompetitorID: 3, Target: 4, Shot: 2},
+		Event{Timestamp: testTime(11, 0, 1, 0), ID: EventJuryDisqualified, CompetitorID: 3, Comment: "Unsafe rifle handling"},
+		Event{Timestamp: testTime(11, 0, 2, 0), ID: EventTimePenalty, CompetitorID: 4, Penalty: time.Minute, Comment: "Short cut"},
+		Event{Timestamp: testTime(10, 59, 59, 500), ID: 99, CompetitorID: 5, ExtraParamsStr: "future params"},
+	)
+	if len(events) <= binaryBlockRecords {
+		t.Fatalf("Test needs more than %d events to span blocks, got %d", binaryBlockRecords, len(events))
+	}
+
+	text, diagnostics, err := LoadEvents(writeTestEvents(t, events))
+	if err != nil || len(diagnostics) != 0 {
+		t.Fatalf("LoadEvents() error = %v, diagnostics = %v", err, diagnostics)
+	}
+
+	var buf bytes.Buffer
+	metadata := map[string]string{"race": "sprint", "venue": "Oberhof"}
+	if err := WriteBinaryEvents(&buf, text, metadata); err != nil {
+		t.Fatalf("WriteBinaryEvents() error = %v", err)
+	}
+	if buf.Len() >= len(strings.Join(eventLines(text), "\n")) {
+		t.Errorf("Binary log is %d bytes, not smaller than the text", buf.Len())
+	}
+
+	decoded, diagnostics, gotMetadata, err := ReadBinaryEvents(&buf, "events.bin")
+	if err != nil {
+		t.Fatalf("ReadBinaryEvents() error = %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf("ReadBinaryEvents() diagnostics = %v", diagnostics)
+	}
+	if gotMetadata["race"] != "sprint" || gotMetadata["venue"] != "Oberhof" || len(gotMetadata) != 2 {
+		t.Errorf("ReadBinaryEvents() metadata = %v, want %v", gotMetadata, metadata)
+	}
+	if len(decoded) != len(text) {
+		t.Fatalf("ReadBinaryEvents() got %d events, want %d", len(decoded), len(text))
+	}
+	for i := range text {
+		want, got := text[i], decoded[i]
+		if got.Line != want.Line || !got.Timestamp.Equal(want.Timestamp) || got.ExtraParamsStr != want.ExtraParamsStr {
+			t.Fatalf("Event %d = %q, want %q", i, got.Line, want.Line)
+		}
+		if got.Target != want.Target || got.Shot != want.Shot || got.Penalty != want.Penalty || got.Comment != want.Comment {
+			t.Fatalf("Event %d params = %+v, want %+v", i, got, want)
+		}
+		if got.Source.Line != i+1 {
+			t.Fatalf("Event %d source line = %d, want %d", i, got.Source.Line, i+1)
+		}
+	}
+}
+
+func eventLines(events []Event) []string {
+	lines := make([]string, len(events))
+	for i, event := range events {
+		lines[i] = event.Line
+	}
+	return lines
+}
+
+func TestLoadEvents_DetectsBinary(t *testing.T) {
+	events := []Event{
+		{Timestamp: testTime(9, 30, 0, 0), ID: EventRegistered, CompetitorID: 1},
+		{Timestamp: testTime(9, 31, 0, 0), ID: EventStartTimeSet, CompetitorID: 1, ScheduledStartTime: testTime(10, 0, 0, 0)},
+		{Timestamp: testTime(10, 0, 1, 0), ID: EventStarted, CompetitorID: 1},
+	}
+	var buf bytes.Buffer
+	if err := WriteBinaryEvents(&buf, events, nil); err != nil {
+		t.Fatalf("WriteBinaryEvents() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "events.bin")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write binary events: %v", err)
+	}
+
+	loaded, diagnostics, err := LoadEvents(path)
+	if err != nil || len(diagnostics) != 0 {
+		t.Fatalf("LoadEvents() error = %v, diagnostics = %v", err, diagnostics)
+	}
+	if len(loaded) != len(events) {
+		t.Fatalf("LoadEvents() got %d events, want %d", len(loaded), len(events))
+	}
+	if !loaded[1].ScheduledStartTime.Equal(events[1].ScheduledStartTime) {
+		t.Errorf("Scheduled start = %v, want %v", loaded[1].ScheduledStartTime, events[1].ScheduledStartTime)
+	}
+	if loaded[2].Line != "[10:00:01.000] 4 1" {
+		t.Errorf("Line = %q, want %q", loaded[2].Line, "[10:00:01.000] 4 1")
+	}
+}
+
+func TestReadBinaryEvents_Corruption(t *testing.T) {
+	events := make([]Event, binaryBlockRecords*3)
+	for i := range events {
+		events[i] = Event{Timestamp: testTime(10, 0, 0, 0).Add(time.Duration(i) * time.Second), ID: EventTargetHit, CompetitorID: i%5 + 1, Target: i%5 + 1}
+	}
+	var buf bytes.Buffer
+	if err := WriteBinaryEvents(&buf, events, map[string]string{"race": "test"}); err != nil {
+		t.Fatalf("WriteBinaryEvents() error = %v", err)
+	}
+	data := buf.Bytes()
+
+	t.Run("Block", func(t *testing.T) {
+		corrupt := bytes.Clone(data)
+		corrupt[len(corrupt)/2] ^= 0xff
+
+		decoded, diagnostics, _, err := ReadBinaryEvents(bytes.NewReader(corrupt), "events.bin")
+		if err != nil {
+			t.Fatalf("ReadBinaryEvents() error = %v", err)
+		}
+		if len(diagnostics) != 1 || diagnostics[0].Kind != DiagnosticChecksumMismatch {
+			t.Fatalf("ReadBinaryEvents() diagnostics = %v, want one %s", diagnostics, DiagnosticChecksumMismatch)
+		}
+		if diagnostics[0].Line != binaryBlockRecords+1 {
+			t.Errorf("Diagnostic record = %d, want %d", diagnostics[0].Line, binaryBlockRecords+1)
+		}
+		if len(decoded) != 2*binaryBlockRecords {
+			t.Fatalf("ReadBinaryEvents() got %d events, want %d", len(decoded), 2*binaryBlockRecords)
+		}
+		last := decoded[len(decoded)-1]
+		if !last.Timestamp.Equal(events[len(events)-1].Timestamp) || last.Source.Line != len(events) {
+			t.Errorf("Last event = %q at record %d, want %q at record %d", last.Line, last.Source.Line, FormatEventLine(events[len(events)-1]), len(events))
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		decoded, diagnostics, _, err := ReadBinaryEvents(bytes.NewReader(data[:len(data)-10]), "events.bin")
+		if err != nil {
+			t.Fatalf("ReadBinaryEvents() error = %v", err)
+		}
+		if len(diagnostics) != 1 || diagnostics[0].Kind != DiagnosticMalformedRecord {
+			t.Fatalf("ReadBinaryEvents() diagnostics = %v, want one %s", diagnostics, DiagnosticMalformedRecord)
+		}
+		if len(decoded) != 2*binaryBlockRecords {
+			t.Errorf("ReadBinaryEvents() got %d events, want %d", len(decoded), 2*binaryBlockRecords)
+		}
+	})
+
+	t.Run("HugeLength", func(t *testing.T) {
+		var empty bytes.Buffer
+		if err := WriteBinaryEvents(&empty, nil, nil); err != nil {
+			t.Fatalf("WriteBinaryEvents() error = %v", err)
+		}
+		// Drop the end marker and add a block whose length overflows when
+		// the checksum size is added to it.
+		corrupt := empty.Bytes()[:empty.Len()-1]
+		corrupt = binary.AppendUvarint(corrupt, 1)
+		corrupt = binary.AppendUvarint(corrupt, math.MaxUint64)
+		corrupt = append(corrupt, 0, 0, 0, 0)
+
+		decoded, diagnostics, _, err := ReadBinaryEvents(bytes.NewReader(corrupt), "events.bin")
+		if err != nil {
+			t.Fatalf("ReadBinaryEvents() error = %v", err)
+		}
+		if len(decoded) != 0 || len(diagnostics) != 1 || diagnostics[0].Kind != DiagnosticMalformedRecord {
+			t.Fatalf("ReadBinaryEvents() = %v, %v, want one %s", decoded, diagnostics, DiagnosticMalformedRecord)
+		}
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		corrupt := bytes.Clone(data)
+		corrupt[len(BinaryLogMagic)+3] ^= 0xff
+		if _, _, _, err := ReadBinaryEvents(bytes.NewReader(corrupt), "events.bin"); err == nil {
+			t.Fatal("ReadBinaryEvents() expected an error for a corrupted header")
+		}
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		corrupt := bytes.Clone(data)
+		corrupt[len(BinaryLogMagic)] = BinaryLogVersion + 1
+		if _, _, _, err := ReadBinaryEvents(bytes.NewReader(corrupt), "events.bin"); err == nil || !strings.Contains(err.Error(), "version") {
+			t.Fatalf("ReadBinaryEvents() error = %v, want a version error", err)
+		}
+	})
+}
+
+func TestWriteMetadata_SortsKeys(t *testing.T) {
+	var out bytes.Buffer
+	WriteMetadata(&out, map[string]string{"race": "oberhof-sprint", "date": "2026-01-09", "venue": "Oberhof"})
+	want := "date: 2026-01-09\nrace: oberhof-sprint\nvenue: Oberhof\n"
+	if out.String() != want {
+		t.Errorf("WriteMetadata() = %q, want %q", out.String(), want)
+	}
+}
@@ -18,6 +18,7 @@ const (
 	StatusNotStarted   CompetitorStatus = "NotStarted"
 	StatusNotFinished  CompetitorStatus = "NotFinished"
 	StatusDisqualified CompetitorStatus = "Disqualified"
+	StatusLapped       CompetitorStatus = "Lapped"
 )
 
 type LapRecord struct {
@@ -33,13 +34,57 @@ type LapRecord struct {
 	AverageSpeed float64
 }
 
+type ShotRecord struct {
+	Time    time.Time
+	Target  int
+	Outcome ShotOutcome
+	Spare   bool
+}
+
 type ShootingRecord struct {
 	RangeID           int
 	EntryTime         time.Time
 	ExitTime          time.Time
 	Hits              int
 	Shots             int
+	SparesUsed        int
 	PenaltiesIncurred int
+	ShotSequence      []ShotRecord
+}
+
+func (sr ShootingRecord) Accuracy() float64 {
+	if sr.Shots == 0 {
+		return 0
+	}
+	return float64(sr.Hits) / float64(sr.Shots)
+}
+
+// Sequence renders the recorded shots in firing order, "X" for a hit and "0"
+// for a miss. It is empty when the stage had no per-shot results.
+func (sr ShootingRecord) Sequence() string {
+	var b strings.Builder
+	for _, shot := range sr.ShotSequence {
+		switch shot.Outcome {
+		case ShotHit:
+			b.WriteByte('X')
+		case ShotMiss:
+			b.WriteByte('0')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// Rhythm returns the average interval between consecutive shots, or zero when
+// fewer than two shots were recorded.
+func (sr ShootingRecord) Rhythm() time.Duration {
+	if len(sr.ShotSequence) < 2 {
+		return 0
+	}
+	first := sr.ShotSequence[0].Time
+	last := sr.ShotSequence[len(sr.ShotSequence)-1].Time
+	return last.Sub(first) / time.Duration(len(sr.ShotSequence)-1)
 }
 
 type PenaltyData struct {
@@ -63,7 +108,10 @@ type Competitor struct {
 		LapStartTime     time.Time
 		RangeEntryTime   time.Time
 		ShotsInSession   int
+		SparesInSession  int
 		HitsInSession    int
+		TargetsHit       []int
+		ShotsCounted     int
 		PenaltiesToServe int
 		PenaltyEntryTime time.Time
 	}
@@ -74,6 +122,11 @@ type Competitor struct {
 
 	DNFComment             string
 	DisqualificationReason string
+	TimeAdjustment         time.Duration
+	Corrected              bool
+	LappedRank             int
+
+	Attributes map[string]string
 
 	GeneratedEvents []Event
 }
@@ -114,7 +167,7 @@ func (c *Competitor) FormatLapResults(config *Config) string {
 		if i < len(c.LapsData) {
 			lap := c.LapsData[i]
 			if !lap.EndTime.IsZero() {
-				lapStrings = append(lapStrings, fmt.Sprintf("{%s, %.3f}", FormatDuration(lap.LapDuration), lap.AverageSpeed))
+				lapStrings = append(lapStrings, fmt.Sprintf("{%s, %.3f}", config.Timing().Format(lap.LapDuration), lap.AverageSpeed))
 			} else if !lap.StartTime.IsZero() && c.Status == StatusNotFinished {
 				lapStrings = append(lapStrings, "{,}")
 			} else {
@@ -152,7 +205,15 @@ func (c *Competitor) CalculatePenaltyStats(config *Config) PenaltyData {
 	}
 }
 
-func (c *Competitor) GetOverallStatusForReport() string {
+func (c *Competitor) GetOverallStatusForReport(config *Config) string {
+	status := c.overallStatus(config)
+	if c.Corrected {
+		status += "*"
+	}
+	return status
+}
+
+func (c *Competitor) overallStatus(config *Config) string {
 	if c.Status == StatusDisqualified {
 		return fmt.Sprintf("[%s]", "Disqualified")
 	}
@@ -163,12 +224,26 @@ func (c *Competitor) GetOverallStatusForReport() string {
 		return fmt.Sprintf("[%s]", "NotStarted")
 	}
 	if c.Status == StatusCompleted && !c.FinishTime.IsZero() {
-		totalRaceTime := c.FinishTime.Sub(c.ActualStartTime)
-		return FormatDuration(totalRaceTime)
+		totalRaceTime := RaceTime(c)
+		return config.Timing().Format(totalRaceTime)
 	}
 	return fmt.Sprintf("[%s]", c.Status)
 }
 
+func (c *Competitor) Attribute(key string) string {
+	if value, ok := c.Attributes[key]; ok && value != "" {
+		return value
+	}
+	return "Unassigned"
+}
+
+func (c *Competitor) ShootingAccuracy() float64 {
+	if c.TotalShots == 0 {
+		return 0
+	}
+	return float64(c.TotalHits) / float64(c.TotalShots)
+}
+
 func (c *Competitor) FinalShootingString() string {
 	return fmt.Sprintf("%d/%d", c.TotalHits, c.TotalShots)
 }
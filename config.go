@@ -7,6 +7,16 @@ import (
 	"time"
 )
 
+const (
+	ModeIndividual = "individual"
+	ModeRelay      = "relay"
+)
+
+const (
+	RoundingTruncate = "truncate"
+	RoundingRound    = "round"
+)
+
 type Config struct {
 	Laps          int    `json:"laps"`
 	LapLen        int    `json:"lapLen"`
@@ -15,8 +25,16 @@ type Config struct {
 	StartStr      string `json:"start"`
 	StartDeltaStr string `json:"startDelta"`
 
-	StartTime  time.Time
-	StartDelta time.Duration
+	Mode               string   `json:"mode,omitempty"`
+	Discipline         string   `json:"discipline,omitempty"`
+	GroupBy            []string `json:"groupBy,omitempty"`
+	ShootingPositions  []string `json:"shootingPositions,omitempty"`
+	TimingPrecisionStr string   `json:"timingPrecision,omitempty"`
+	TimingRounding     string   `json:"timingRounding,omitempty"`
+
+	StartTime       time.Time
+	StartDelta      time.Duration
+	TimingPrecision time.Duration
 }
 
 func LoadConfig(filePath string) (*Config, error) {
@@ -41,5 +59,37 @@ func LoadConfig(filePath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config StartDelta '%s': %w", cfg.StartDeltaStr, err)
 	}
 
+	if cfg.TimingPrecisionStr != "" {
+		cfg.TimingPrecision, err = time.ParseDuration(cfg.TimingPrecisionStr)
+		if err != nil || cfg.TimingPrecision <= 0 {
+			return nil, fmt.Errorf("invalid timing precision '%s' in config '%s'", cfg.TimingPrecisionStr, filePath)
+		}
+	}
+
+	if cfg.TimingRounding != "" && cfg.TimingRounding != RoundingTruncate && cfg.TimingRounding != RoundingRound {
+		return nil, fmt.Errorf("unknown timing rounding '%s' in config '%s', expected truncate or round", cfg.TimingRounding, filePath)
+	}
+
+	if cfg.Mode == "" {
+		cfg.Mode = ModeIndividual
+	}
+	if cfg.Mode != ModeIndividual && cfg.Mode != ModeRelay {
+		return nil, fmt.Errorf("unknown race mode '%s' in config '%s'", cfg.Mode, filePath)
+	}
+
 	return &cfg, nil
 }
+
+// Timing returns the policy official times are computed with: truncation to
+// tenths of a second unless configured otherwise.
+func (c *Config) Timing() TimingPolicy {
+	policy := TimingPolicy{Precision: c.TimingPrecision, Round: c.TimingRounding == RoundingRound}
+	if policy.Precision <= 0 {
+		policy.Precision = DefaultTimingPrecision
+	}
+	return policy
+}
+
+func (c *Config) IsRelay() bool {
+	return c.Mode == ModeRelay
+}
@@ -37,6 +37,15 @@ func TestLoadConfig(t *testing.T) {
 		t.Fatalf("Failed to write temp config file: %v", err)
 	}
 
+	invalidRoundingConfigContent := `{
+		"laps": 1, "lapLen": 1000, "penaltyLen": 100, "firingLines": 1,
+		"start": "10:00:00", "startDelta": "00:00:30", "timingRounding": "ceil"
+	}`
+	invalidRoundingConfigPath := filepath.Join(tempDir, "invalid_rounding_config.json")
+	if err := os.WriteFile(invalidRoundingConfigPath, []byte(invalidRoundingConfigContent), 0644); err != nil {
+		t.Fatalf("Failed to write temp config file: %v", err)
+	}
+
 	tests := []struct {
 		name       string
 		filePath   string
@@ -50,6 +59,7 @@ func TestLoadConfig(t *testing.T) {
 		{"FileNotFound", filepath.Join(tempDir, "non_existent_config.json"), 0, 0, 0, 0, true},
 		{"InvalidJSON", invalidJsonConfigPath, 0, 0, 0, 0, true},
 		{"InvalidTimeFormat", invalidTimeConfigPath, 0, 0, 0, 0, true},
+		{"InvalidTimingRounding", invalidRoundingConfigPath, 0, 0, 0, 0, true},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CorrectionAction string
+
+const (
+	CorrectionAdjustFinish CorrectionAction = "adjust-finish"
+	CorrectionVoidLine     CorrectionAction = "void-line"
+	CorrectionAddPenalty   CorrectionAction = "add-penalty"
+	CorrectionReinstate    CorrectionAction = "reinstate"
+)
+
+// Correction is one official change to the race record. Corrections are
+// written one per line as
+//
+//	<action> <target> [amount] | <reason> | <official>
+//
+// where the target is a competitor ID, or an events file line number for
+// void-line, e.g. "adjust-finish 5 +10s | Photo finish review | J. Smith".
+// When events are merged from several files the line can be given as
+// "<file name>:<line>".
+type Correction struct {
+	Action       CorrectionAction
+	CompetitorID int
+	File         string
+	LineNumber   int
+	Amount       time.Duration
+	Reason       string
+	Official     string
+
+	Source SourcePos
+}
+
+// CorrectionAudit records the outcome of one correction. EventSource is the
+// position of the event the correction removed, for void-line corrections.
+type CorrectionAudit struct {
+	Correction  Correction
+	EventSource SourcePos
+	Original    string
+	Corrected   string
+	Applied     bool
+	Note        string
+}
+
+func LoadCorrections(filePath string) ([]Correction, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corrections file '%s': %w", filePath, err)
+	}
+	defer file.Close()
+
+	var corrections []Correction
+	scanner := newPositionScanner(file, filePath)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		correction, err := ParseCorrection(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid correction on line %d of '%s': %w", scanner.Pos.Line, filePath, err)
+		}
+		correction.Source = scanner.Pos
+		corrections = append(corrections, correction)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading corrections file '%s': %w", filePath, err)
+	}
+	return corrections, nil
+}
+
+func ParseCorrection(line string) (Correction, error) {
+	parts := strings.Split(line, "|")
+	if len(parts) != 3 {
+		return Correction{}, fmt.Errorf("expected '<action> <target> [amount] | <reason> | <official>'")
+	}
+	correction := Correction{
+		Reason:   strings.TrimSpace(parts[1]),
+		Official: strings.TrimSpace(parts[2]),
+	}
+	if correction.Reason == "" || correction.Official == "" {
+		return Correction{}, fmt.Errorf("reason and official are required")
+	}
+
+	fields := strings.Fields(parts[0])
+	if len(fields) < 2 {
+		return Correction{}, fmt.Errorf("missing action or target")
+	}
+	correction.Action = CorrectionAction(fields[0])
+	targetStr := fields[1]
+	if correction.Action == CorrectionVoidLine {
+		if file, line, ok := strings.Cut(targetStr, ":"); ok {
+			correction.File = file
+			targetStr = line
+		}
+	}
+	target, err := strconv.Atoi(targetStr)
+	if err != nil {
+		return Correction{}, fmt.Errorf("invalid target '%s': %w", fields[1], err)
+	}
+
+	wantAmount := false
+	switch correction.Action {
+	case CorrectionAdjustFinish, CorrectionAddPenalty:
+		correction.CompetitorID = target
+		wantAmount = true
+	case CorrectionReinstate:
+		correction.CompetitorID = target
+	case CorrectionVoidLine:
+		correction.LineNumber = target
+	default:
+		return Correction{}, fmt.Errorf("unknown action '%s'", fields[0])
+	}
+
+	if wantAmount != (len(fields) == 3) || len(fields) > 3 {
+		return Correction{}, fmt.Errorf("wrong number of arguments for '%s'", correction.Action)
+	}
+	if wantAmount {
+		correction.Amount, err = time.ParseDuration(fields[2])
+		if err != nil {
+			return Correction{}, fmt.Errorf("invalid amount '%s': %w", fields[2], err)
+		}
+	}
+	return correction, nil
+}
+
+func (c Correction) String() string {
+	switch c.Action {
+	case CorrectionVoidLine:
+		if c.File != "" {
+			return fmt.Sprintf("%s %s:%d", c.Action, c.File, c.LineNumber)
+		}
+		return fmt.Sprintf("%s %d", c.Action, c.LineNumber)
+	case CorrectionReinstate:
+		return fmt.Sprintf("%s %d", c.Action, c.CompetitorID)
+	default:
+		return fmt.Sprintf("%s %d %+v", c.Action, c.CompetitorID, c.Amount)
+	}
+}
+
+// voids reports whether a void-line correction targets the event. Without a
+// file name the line number matches in any events file, so such corrections
+// are only applied when the events come from a single file.
+func (c Correction) voids(event Event) bool {
+	if c.Action != CorrectionVoidLine || event.Source.Line == 0 || event.Source.Line != c.LineNumber {
+		return false
+	}
+	return c.File == "" || c.File == event.Station || c.File == filepath.Base(event.Source.File) || c.File == event.Source.File
+}
+
+// ApplyEventCorrections removes voided events from the raw log before it is
+// simulated. It returns the remaining events and an audit entry per void.
+func ApplyEventCorrections(events []Event, corrections []Correction) ([]Event, []CorrectionAudit) {
+	files := make(map[string]bool)
+	for _, event := range events {
+		if event.Source.File != "" {
+			files[event.Source.File] = true
+		}
+	}
+
+	var audit []CorrectionAudit
+	var voids []int
+	for i, correction := range corrections {
+		if correction.Action != CorrectionVoidLine {
+			continue
+		}
+		if correction.File == "" && len(files) > 1 {
+			audit = append(audit, CorrectionAudit{
+				Correction: correction,
+				Note:       fmt.Sprintf("line %d is ambiguous across %d events files, use <file>:<line>", correction.LineNumber, len(files)),
+			})
+			continue
+		}
+		voids = append(voids, i)
+	}
+	if len(voids) == 0 {
+		return events, audit
+	}
+
+	found := make(map[int]bool)
+	kept := make([]Event, 0, len(events))
+	for _, event := range events {
+		voided := false
+		for _, idx := range voids {
+			if corrections[idx].voids(event) {
+				found[idx] = true
+				voided = true
+				audit = append(audit, CorrectionAudit{
+					Correction:  corrections[idx],
+					EventSource: event.Source,
+					Original:    event.Line,
+					Corrected:   "(void)",
+					Applied:     true,
+				})
+				break
+			}
+		}
+		if !voided {
+			kept = append(kept, event)
+		}
+	}
+
+	for _, idx := range voids {
+		if !found[idx] {
+			correction := corrections[idx]
+			audit = append(audit, CorrectionAudit{
+				Correction: correction,
+				Note:       fmt.Sprintf("no event on line %d", correction.LineNumber),
+			})
+		}
+	}
+	return kept, audit
+}
+
+// ApplyCorrections applies the competitor corrections to the simulated race.
+// It is meant to run after Run and before FinalizeResults.
+func (s *Simulation) ApplyCorrections(corrections []Correction) []CorrectionAudit {
+	var audit []CorrectionAudit
+	timing := s.Config.Timing()
+
+	for _, correction := range corrections {
+		if correction.Action == CorrectionVoidLine {
+			continue
+		}
+		entry := CorrectionAudit{Correction: correction}
+		c, ok := s.Competitors[correction.CompetitorID]
+		if !ok {
+			entry.Note = fmt.Sprintf("unknown competitor %d", correction.CompetitorID)
+			audit = append(audit, entry)
+			continue
+		}
+
+		switch correction.Action {
+		case CorrectionAdjustFinish:
+			if c.FinishTime.IsZero() {
+				entry.Note = fmt.Sprintf("competitor %d has no finish time", c.ID)
+				break
+			}
+			entry.Original = FormatTime(c.FinishTime)
+			c.FinishTime = c.FinishTime.Add(correction.Amount)
+			if n := len(c.LapsData); n > 0 && !c.LapsData[n-1].EndTime.IsZero() {
+				c.LapsData[n-1].EndTime = c.LapsData[n-1].EndTime.Add(correction.Amount)
+			}
+			entry.Corrected = FormatTime(c.FinishTime)
+			entry.Applied = true
+
+		case CorrectionAddPenalty:
+			entry.Original = timing.Format(c.TimeAdjustment)
+			c.TimeAdjustment += correction.Amount
+			entry.Corrected = timing.Format(c.TimeAdjustment)
+			entry.Applied = true
+
+		case CorrectionReinstate:
+			if c.Status != StatusDisqualified {
+				entry.Note = fmt.Sprintf("competitor %d is %s, not disqualified", c.ID, c.Status)
+				break
+			}
+			entry.Original = string(c.Status)
+			c.Status = reinstatedStatus(c)
+			c.DisqualificationReason = ""
+			entry.Corrected = string(c.Status)
+			entry.Applied = true
+		}
+
+		if entry.Applied {
+			c.Corrected = true
+		}
+		audit = append(audit, entry)
+	}
+	return audit
+}
+
+// reinstatedStatus is the status a disqualified competitor returns to, based
+// on how far they got in the race.
+func reinstatedStatus(c *Competitor) CompetitorStatus {
+	switch {
+	case !c.FinishTime.IsZero():
+		return StatusCompleted
+	case c.DNFComment != "":
+		return StatusNotFinished
+	case !c.ActualStartTime.IsZero():
+		return StatusRacing
+	default:
+		return StatusNotStarted
+	}
+}
+
+func GenerateCorrectionsLog(w io.Writer, audit []CorrectionAudit) {
+	fmt.Fprintln(w, "Corrections")
+	fmt.Fprintln(w, "-----------")
+	for _, entry := range audit {
+		correction := entry.Correction
+		result := fmt.Sprintf("%s -> %s", entry.Original, entry.Corrected)
+		if !entry.Applied {
+			result = "not applied: " + entry.Note
+		}
+		if entry.EventSource.Line > 0 {
+			result += fmt.Sprintf(" at %s", entry.EventSource)
+		}
+		fmt.Fprintf(w, "%s: %s (%s) by %s: %s\n", correction.Source, correction, correction.Reason, correction.Official, result)
+	}
+	fmt.Fprintln(w)
+}
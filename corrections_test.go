@@ -0,0 +1,189 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCorrection(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Correction
+		wantErr bool
+	}{
+		{
+			name: "AdjustFinish",
+			line: "adjust-finish 5 +10s | Timing loop misread | J. Smith",
+			want: Correction{Action: CorrectionAdjustFinish, CompetitorID: 5, Amount: 10 * time.Second, Reason: "Timing loop misread", Official: "J. Smith"},
+		},
+		{
+			name: "AdjustFinishNegative",
+			line: "adjust-finish 5 -1.5s | Photo finish | Jury",
+			want: Correction{Action: CorrectionAdjustFinish, CompetitorID: 5, Amount: -1500 * time.Millisecond, Reason: "Photo finish", Official: "Jury"},
+		},
+		{
+			name: "VoidLine",
+			line: "void-line 42 | Duplicate finish impulse | J. Smith",
+			want: Correction{Action: CorrectionVoidLine, LineNumber: 42, Reason: "Duplicate finish impulse", Official: "J. Smith"},
+		},
+		{
+			name: "VoidLineInFile",
+			line: "void-line finish.log:7 | Double impulse | J. Smith",
+			want: Correction{Action: CorrectionVoidLine, File: "finish.log", LineNumber: 7, Reason: "Double impulse", Official: "J. Smith"},
+		},
+		{
+			name: "Reinstate",
+			line: "reinstate 12 | Protest upheld | Jury",
+			want: Correction{Action: CorrectionReinstate, CompetitorID: 12, Reason: "Protest upheld", Official: "Jury"},
+		},
+		{name: "MissingOfficial", line: "add-penalty 7 2m | Missed penalty loop", wantErr: true},
+		{name: "EmptyReason", line: "add-penalty 7 2m |  | Jury", wantErr: true},
+		{name: "UnknownAction", line: "remove 7 | Because | Jury", wantErr: true},
+		{name: "MissingAmount", line: "add-penalty 7 | Missed penalty loop | Jury", wantErr: true},
+		{name: "UnexpectedAmount", line: "reinstate 7 1m | Protest upheld | Jury", wantErr: true},
+		{name: "InvalidAmount", line: "add-penalty 7 two | Missed penalty loop | Jury", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCorrection(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCorrection() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseCorrection() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCorrections(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corrections")
+	content := "# official corrections\n\nadd-penalty 7 2m | Missed penalty loop | Jury\nbogus line\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadCorrections(path)
+	if err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Fatalf("Expected error on line 4, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(content[:strings.Index(content, "bogus")]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corrections, err := LoadCorrections(path)
+	if err != nil {
+		t.Fatalf("LoadCorrections() error = %v", err)
+	}
+	if len(corrections) != 1 || corrections[0].Source.Line != 3 || corrections[0].Amount != 2*time.Minute {
+		t.Errorf("Unexpected corrections: %+v", corrections)
+	}
+}
+
+func TestApplyEventCorrections(t *testing.T) {
+	events := []Event{
+		{ID: EventRegistered, CompetitorID: 1, Line: "[09:00:00.000] 1 1", Source: SourcePos{Line: 1}},
+		{ID: EventRegistered, CompetitorID: 2, Line: "[09:00:01.000] 1 2", Source: SourcePos{Line: 2}},
+	}
+	corrections := []Correction{
+		{Action: CorrectionVoidLine, LineNumber: 2},
+		{Action: CorrectionVoidLine, LineNumber: 9},
+		{Action: CorrectionVoidLine, File: "other", LineNumber: 1},
+		{Action: CorrectionAddPenalty, CompetitorID: 1, Amount: time.Minute},
+	}
+
+	kept, audit := ApplyEventCorrections(events, corrections)
+	if len(kept) != 1 || kept[0].CompetitorID != 1 {
+		t.Fatalf("Expected only competitor 1's event to remain, got %+v", kept)
+	}
+	if len(audit) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d", len(audit))
+	}
+	if !audit[0].Applied || audit[0].Original != events[1].Line || audit[0].EventSource != events[1].Source {
+		t.Errorf("Unexpected audit for voided line: %+v", audit[0])
+	}
+	if audit[1].Applied || audit[1].Note == "" {
+		t.Errorf("Expected missing line to be reported, got %+v", audit[1])
+	}
+}
+
+func TestApplyEventCorrections_SeveralFiles(t *testing.T) {
+	events := []Event{
+		{ID: EventStarted, CompetitorID: 1, Source: SourcePos{File: "stations/start.log", Line: 7}},
+		{ID: EventOnFiringRange, CompetitorID: 1, Source: SourcePos{File: "stations/range.log", Line: 7}},
+		{ID: EventEndedMainLap, CompetitorID: 1, Source: SourcePos{File: "stations/finish.log", Line: 7}},
+	}
+	corrections := []Correction{
+		{Action: CorrectionVoidLine, LineNumber: 7},
+		{Action: CorrectionVoidLine, File: "range.log", LineNumber: 7},
+	}
+
+	kept, audit := ApplyEventCorrections(events, corrections)
+	if len(kept) != 2 || kept[0].ID != EventStarted || kept[1].ID != EventEndedMainLap {
+		t.Fatalf("Expected only the range event to be voided, got %+v", kept)
+	}
+	if len(audit) != 2 {
+		t.Fatalf("Expected 2 audit entries, got %d", len(audit))
+	}
+	if audit[0].Applied || !strings.Contains(audit[0].Note, "ambiguous") {
+		t.Errorf("Expected the unqualified line to be reported as ambiguous, got %+v", audit[0])
+	}
+	if !audit[1].Applied || audit[1].EventSource != events[1].Source {
+		t.Errorf("Unexpected audit for the qualified line: %+v", audit[1])
+	}
+}
+
+func TestSimulation_ApplyCorrections(t *testing.T) {
+	cfg := createTestConfig()
+	sim := NewSimulation(cfg)
+
+	finisher := finishedCompetitor(1, 30*time.Minute, nil)
+	finisher.LapsData = []LapRecord{{LapNumber: 1, StartTime: finisher.ActualStartTime, EndTime: finisher.FinishTime}}
+	penalised := finishedCompetitor(2, 29*time.Minute, nil)
+	dsq := finishedCompetitor(3, 31*time.Minute, nil)
+	dsq.Status = StatusDisqualified
+	dsq.DisqualificationReason = "Missed penalty loop"
+	sim.Competitors = map[int]*Competitor{1: finisher, 2: penalised, 3: dsq}
+
+	audit := sim.ApplyCorrections([]Correction{
+		{Action: CorrectionAdjustFinish, CompetitorID: 1, Amount: -2 * time.Second},
+		{Action: CorrectionAddPenalty, CompetitorID: 2, Amount: 2 * time.Minute},
+		{Action: CorrectionReinstate, CompetitorID: 3},
+		{Action: CorrectionReinstate, CompetitorID: 1},
+		{Action: CorrectionAddPenalty, CompetitorID: 99, Amount: time.Minute},
+		{Action: CorrectionVoidLine, LineNumber: 4},
+	})
+
+	if len(audit) != 5 {
+		t.Fatalf("Expected 5 audit entries, got %d", len(audit))
+	}
+	if RaceTime(finisher) != 30*time.Minute-2*time.Second {
+		t.Errorf("Expected adjusted race time, got %v", RaceTime(finisher))
+	}
+	if !finisher.LapsData[0].EndTime.Equal(finisher.FinishTime) {
+		t.Errorf("Expected last lap end to follow the finish time")
+	}
+	if RaceTime(penalised) != 31*time.Minute {
+		t.Errorf("Expected penalty in race time, got %v", RaceTime(penalised))
+	}
+	if dsq.Status != StatusCompleted || dsq.DisqualificationReason != "" {
+		t.Errorf("Expected reinstated finisher, got %s (%q)", dsq.Status, dsq.DisqualificationReason)
+	}
+	for _, i := range []int{3, 4} {
+		if audit[i].Applied || audit[i].Note == "" {
+			t.Errorf("Expected audit %d to be rejected with a note, got %+v", i, audit[i])
+		}
+	}
+	for _, c := range []*Competitor{finisher, penalised, dsq} {
+		if !c.Corrected {
+			t.Errorf("Expected competitor %d to be marked corrected", c.ID)
+		}
+	}
+	if got := penalised.GetOverallStatusForReport(cfg); got != "00:31:00.0*" {
+		t.Errorf("Expected annotated result, got %q", got)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+type DiagnosticKind string
+
+const (
+	DiagnosticMalformedLine       DiagnosticKind = "malformed-line"
+	DiagnosticInvalidTimestamp    DiagnosticKind = "invalid-timestamp"
+	DiagnosticInvalidEventID      DiagnosticKind = "invalid-event-id"
+	DiagnosticInvalidCompetitorID DiagnosticKind = "invalid-competitor-id"
+	DiagnosticInvalidParams       DiagnosticKind = "invalid-params"
+)
+
+// Diagnostic describes a problem found on one line of an events file: a parse
+// error or a lint rule violation, with the rule ID as its kind. Columns are
+// 1-based byte positions in the raw line and Offset is the byte offset of the
+// column in the file. An empty severity means error.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Offset   int64
+	Raw      string
+	Kind     DiagnosticKind
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) IsError() bool {
+	return d.Severity == "" || d.Severity == SeverityError
+}
+
+// String renders the diagnostic in the usual compiler style,
+// "file:line:col: severity: message [kind]".
+func (d Diagnostic) String() string {
+	severity := d.Severity
+	if severity == "" {
+		severity = SeverityError
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", d.File, d.Line, d.Column, severity, d.Message, d.Kind)
+}
+
+func (d Diagnostic) Error() string {
+	return d.String()
+}
+
+// WriteDiagnostics prints each diagnostic followed by the offending line and a
+// caret under the reported column.
+func WriteDiagnostics(w io.Writer, diagnostics []Diagnostic) error {
+	for _, d := range diagnostics {
+		caret := ""
+		for i := 1; i < d.Column && i <= len(d.Raw); i++ {
+			if d.Raw[i-1] == '\t' {
+				caret += "\t"
+			} else {
+				caret += " "
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s\n    %s\n    %s^\n", d, d.Raw, caret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
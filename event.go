@@ -3,11 +3,12 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 type EventID int
@@ -24,9 +25,24 @@ const (
 	EventLeftPenaltyLaps    EventID = 9
 	EventEndedMainLap       EventID = 10
 	EventCannotContinue     EventID = 11
+	EventRelayHandOff       EventID = 12
+	EventShotFired          EventID = 13
+	EventSpareLoaded        EventID = 14
+	EventJuryDisqualified   EventID = 15
+	EventTimePenalty        EventID = 16
+	EventSyncPulse          EventID = 17
 
 	EventDisqualified EventID = 32
 	EventFinished     EventID = 33
+	EventLapped       EventID = 34
+)
+
+type ShotOutcome int
+
+const (
+	ShotUnknown ShotOutcome = iota
+	ShotHit
+	ShotMiss
 )
 
 type Event struct {
@@ -35,108 +51,289 @@ type Event struct {
 	CompetitorID   int
 	ExtraParamsStr string
 	Line           string
+	Source         SourcePos
+	Station        string
+	ClockOffset    time.Duration
 
 	ScheduledStartTime time.Time
 	FiringRange        int
 	Target             int
 	Comment            string
+	NextCompetitorID   int
+	Shot               ShotOutcome
+	Penalty            time.Duration
 }
 
-var eventRegex = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2}\.\d{3})\]\s+(\d+)\s+(\d+)(?:\s+(.*))?$`)
-var sourcePrefixRegex = regexp.MustCompile(`^\\s*`)
+// SourcePos locates an event in its events file. Offset is the byte offset of
+// the start of the line.
+type SourcePos struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Offset int64  `json:"offset"`
+}
 
-func LoadEvents(filePath string) ([]Event, error) {
+// String renders the position as "file:line", or "line N" without a file and
+// an empty string for events that did not come from a file.
+func (p SourcePos) String() string {
+	switch {
+	case p.Line == 0:
+		return ""
+	case p.File == "":
+		return fmt.Sprintf("line %d", p.Line)
+	default:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+}
+
+// positionScanner scans lines and keeps the position of the current one.
+type positionScanner struct {
+	*bufio.Scanner
+	Pos SourcePos
+
+	next int64
+}
+
+func newPositionScanner(r io.Reader, file string) *positionScanner {
+	ps := &positionScanner{Scanner: bufio.NewScanner(r), Pos: SourcePos{File: file}}
+	ps.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		ps.next += int64(advance)
+		return advance, token, err
+	})
+	return ps
+}
+
+func (ps *positionScanner) Scan() bool {
+	ps.Pos.Offset = ps.next
+	if !ps.Scanner.Scan() {
+		return false
+	}
+	ps.Pos.Line++
+	return true
+}
+
+// LoadEvents reads an events file in the text or the binary format, detected
+// from its first bytes. Lines that cannot be parsed are skipped and reported
+// as diagnostics; the error is only set when the file cannot be read.
+func LoadEvents(filePath string) ([]Event, []Diagnostic, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open events file '%s': %w", filePath, err)
+		return nil, nil, fmt.Errorf("failed to open events file '%s': %w", filePath, err)
 	}
 	defer file.Close()
 
+	reader, binary := newSniffingReader(file)
+	if binary {
+		events, diagnostics, _, err := ReadBinaryEvents(reader, filePath)
+		return events, diagnostics, err
+	}
+
 	var events []Event
-	scanner := bufio.NewScanner(file)
-	lineNumber := 0
+	var diagnostics []Diagnostic
+	scanner := newPositionScanner(reader, filePath)
 
 	for scanner.Scan() {
-		lineNumber++
-		line := strings.TrimSpace(scanner.Text())
-		originalLine := line
-
-		if line == "" {
-			continue
+		event, diagnostic, ok := ParseEventLine(scanner.Text(), scanner.Pos)
+		if diagnostic != nil {
+			diagnostics = append(diagnostics, *diagnostic)
+		}
+		if ok {
+			events = append(events, event)
 		}
+	}
 
-		line = sourcePrefixRegex.ReplaceAllString(line, "")
+	if err := scanner.Err(); err != nil {
+		return nil, diagnostics, fmt.Errorf("error reading events file '%s': %w", filePath, err)
+	}
 
-		matches := eventRegex.FindStringSubmatch(line)
-		if matches == nil {
-			fmt.Printf("Warning: Skipping malformed event line %d: %s\n", lineNumber, originalLine)
-			continue
-		}
+	return events, diagnostics, nil
+}
 
-		timestampStr := matches[1]
-		eventIDStr := matches[2]
-		competitorIDStr := matches[3]
-		extraParamsStr := ""
-		if len(matches) > 4 {
-			extraParamsStr = strings.TrimSpace(matches[4])
+// ParseEventLine parses one line of an events file. Blank lines yield neither
+// an event nor a diagnostic.
+func ParseEventLine(raw string, pos SourcePos) (Event, *Diagnostic, bool) {
+	line := strings.TrimSpace(raw)
+	if line == "" {
+		return Event{}, nil, false
+	}
+	originalLine := line
+	offset := strings.Index(raw, line)
+
+	stripped := stripSourcePrefix(line)
+	offset += len(line) - len(stripped)
+	line = stripped
+
+	diagnose := func(kind DiagnosticKind, start int, format string, args ...any) (Event, *Diagnostic, bool) {
+		column := offset + 1 + max(start, 0)
+		return Event{}, &Diagnostic{
+			File:     pos.File,
+			Line:     pos.Line,
+			Column:   column,
+			Offset:   pos.Offset + int64(column-1),
+			Raw:      raw,
+			Kind:     kind,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf(format, args...),
+		}, false
+	}
+
+	tokens, ok := tokenizeEventLine(line)
+	if !ok {
+		return diagnose(DiagnosticMalformedLine, 0, "expected '[HH:MM:SS.sss] <eventID> <competitorID> [params]'")
+	}
+
+	timeStr := line[tokens.timeStart:tokens.timeEnd]
+	timestamp, ok := parseClock(timeStr)
+	if !ok {
+		var err error
+		if timestamp, err = ParseTime(timeStr); err != nil {
+			return diagnose(DiagnosticInvalidTimestamp, tokens.timeStart, "invalid timestamp: %v", err)
 		}
+	}
+	eventIDInt, err := strconv.Atoi(line[tokens.idStart:tokens.idEnd])
+	if err != nil {
+		return diagnose(DiagnosticInvalidEventID, tokens.idStart, "invalid event ID: %v", err)
+	}
+	competitorID, err := strconv.Atoi(line[tokens.competitorStart:tokens.competitorEnd])
+	if err != nil {
+		return diagnose(DiagnosticInvalidCompetitorID, tokens.competitorStart, "invalid competitor ID: %v", err)
+	}
 
-		timestamp, err := ParseTime(timestampStr)
+	event := Event{
+		Timestamp:    timestamp,
+		ID:           EventID(eventIDInt),
+		CompetitorID: competitorID,
+		Line:         originalLine,
+		Source:       pos,
+	}
+	if tokens.paramsStart >= 0 {
+		event.ExtraParamsStr = strings.TrimSpace(line[tokens.paramsStart:tokens.paramsEnd])
+	}
+	if err := parseEventParams(&event); err != nil {
+		return diagnose(DiagnosticInvalidParams, tokens.paramsStart, "%v", err)
+	}
+	return event, nil, true
+}
+
+// parseEventParams fills the typed fields of an event from its raw parameter
+// string.
+func parseEventParams(event *Event) error {
+	params := event.ExtraParamsStr
+	var err error
+	switch event.ID {
+	case EventStartTimeSet:
+		event.ScheduledStartTime, err = ParseTime(params)
 		if err != nil {
-			fmt.Printf("Warning: Failed to parse timestamp on line %d ('%s'): %v. Skipping event.\n", lineNumber, originalLine, err)
-			continue
+			return fmt.Errorf("invalid scheduled start time for event %d: %w", event.ID, err)
 		}
-
-		eventIDInt, err := strconv.Atoi(eventIDStr)
+	case EventOnFiringRange:
+		event.FiringRange, err = strconv.Atoi(params)
 		if err != nil {
-			fmt.Printf("Warning: Failed to parse EventID on line %d ('%s'): %v. Skipping event.\n", lineNumber, originalLine, err)
-			continue
+			return fmt.Errorf("invalid firing range for event %d: %w", event.ID, err)
 		}
-
-		competitorID, err := strconv.Atoi(competitorIDStr)
+	case EventTargetHit:
+		event.Target, err = strconv.Atoi(params)
 		if err != nil {
-			fmt.Printf("Warning: Failed to parse CompetitorID on line %d ('%s'): %v. Skipping event.\n", lineNumber, originalLine, err)
-			continue
+			return fmt.Errorf("invalid target for event %d: %w", event.ID, err)
 		}
-
-		event := Event{
-			Timestamp:      timestamp,
-			ID:             EventID(eventIDInt),
-			CompetitorID:   competitorID,
-			ExtraParamsStr: extraParamsStr,
-			Line:           originalLine,
+	case EventCannotContinue, EventJuryDisqualified:
+		event.Comment = params
+	case EventShotFired:
+		if params == "" {
+			break
 		}
-
-		switch event.ID {
-		case EventStartTimeSet:
-			event.ScheduledStartTime, err = ParseTime(extraParamsStr)
-			if err != nil {
-				fmt.Printf("Warning: Failed to parse ScheduledStartTime for event 2 on line %d ('%s'): %v. Skipping event.\n", lineNumber, originalLine, err)
-				continue
-			}
-		case EventOnFiringRange:
-			event.FiringRange, err = strconv.Atoi(extraParamsStr)
-			if err != nil {
-				fmt.Printf("Warning: Failed to parse FiringRange for event 5 on line %d ('%s'): %v. Skipping event.\n", lineNumber, originalLine, err)
-				continue
-			}
-		case EventTargetHit:
-			event.Target, err = strconv.Atoi(extraParamsStr)
-			if err != nil {
-				fmt.Printf("Warning: Failed to parse Target for event 6 on line %d ('%s'): %v. Skipping event.\n", lineNumber, originalLine, err)
-				continue
-			}
-		case EventCannotContinue:
-			event.Comment = extraParamsStr
+		event.Target, event.Shot, err = parseShotParams(params)
+		if err != nil {
+			return fmt.Errorf("invalid shot for event %d: %w", event.ID, err)
+		}
+	case EventRelayHandOff:
+		event.NextCompetitorID, err = strconv.Atoi(params)
+		if err != nil {
+			return fmt.Errorf("invalid next competitor ID for event %d: %w", event.ID, err)
+		}
+	case EventTimePenalty:
+		event.Penalty, event.Comment, err = parsePenaltyParams(params)
+		if err != nil {
+			return fmt.Errorf("invalid time penalty for event %d: %w", event.ID, err)
 		}
-		events = append(events, event)
 	}
+	return nil
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading events file '%s': %w", filePath, err)
+// parseShotParams parses the optional "<target> <hit|miss>" parameters of a
+// shot event.
+func parseShotParams(params string) (int, ShotOutcome, error) {
+	targetStr, result := strings.TrimSpace(params), ""
+	if i := strings.IndexFunc(targetStr, unicode.IsSpace); i >= 0 {
+		targetStr, result = targetStr[:i], strings.TrimSpace(targetStr[i:])
+	}
+	if result == "" || strings.IndexFunc(result, unicode.IsSpace) >= 0 {
+		return 0, ShotUnknown, fmt.Errorf("expected '<target> <hit|miss>', got '%s'", params)
+	}
+	target, err := strconv.Atoi(targetStr)
+	if err != nil {
+		return 0, ShotUnknown, fmt.Errorf("invalid target '%s': %w", targetStr, err)
+	}
+	switch strings.ToLower(result) {
+	case "hit", "x":
+		return target, ShotHit, nil
+	case "miss", "0":
+		return target, ShotMiss, nil
+	default:
+		return 0, ShotUnknown, fmt.Errorf("invalid shot result '%s', expected hit or miss", result)
 	}
+}
+
+// parsePenaltyParams parses the "<duration> [reason]" parameters of a time
+// penalty event. The duration uses Go syntax, e.g. "30s" or "2m".
+func parsePenaltyParams(params string) (time.Duration, string, error) {
+	durationStr, reason, _ := strings.Cut(params, " ")
+	if durationStr == "" {
+		return 0, "", fmt.Errorf("expected '<duration> [reason]', got '%s'", params)
+	}
+	penalty, err := time.ParseDuration(durationStr)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid penalty '%s': %w", durationStr, err)
+	}
+	return penalty, strings.TrimSpace(reason), nil
+}
+
+// FormatEventParams renders the extra parameters of an event in the form
+// LoadEvents expects, falling back to the raw parameter string.
+func FormatEventParams(event Event) string {
+	switch event.ID {
+	case EventStartTimeSet:
+		return FormatTime(event.ScheduledStartTime)
+	case EventOnFiringRange:
+		return strconv.Itoa(event.FiringRange)
+	case EventTargetHit:
+		return strconv.Itoa(event.Target)
+	case EventCannotContinue, EventJuryDisqualified:
+		return event.Comment
+	case EventRelayHandOff:
+		return strconv.Itoa(event.NextCompetitorID)
+	case EventTimePenalty:
+		return strings.TrimSpace(event.Penalty.String() + " " + event.Comment)
+	case EventShotFired:
+		switch event.Shot {
+		case ShotHit:
+			return fmt.Sprintf("%d hit", event.Target)
+		case ShotMiss:
+			return fmt.Sprintf("%d miss", event.Target)
+		}
+		return ""
+	default:
+		return event.ExtraParamsStr
+	}
+}
 
-	return events, nil
+// FormatEventLine renders an event as one line of an events file.
+func FormatEventLine(event Event) string {
+	line := fmt.Sprintf("[%s] %d %d", FormatTime(event.Timestamp), event.ID, event.CompetitorID)
+	if params := FormatEventParams(event); params != "" {
+		line += " " + params
+	}
+	return line
 }
 
 func GetEventDescription(event Event) string {
@@ -163,10 +360,39 @@ func GetEventDescription(event Event) string {
 		return fmt.Sprintf("The competitor(%d) ended the main lap", event.CompetitorID)
 	case EventCannotContinue:
 		return fmt.Sprintf("The competitor(%d) can't continue: %s", event.CompetitorID, event.Comment)
+	case EventRelayHandOff:
+		return fmt.Sprintf("The competitor(%d) handed off to the competitor(%d)", event.CompetitorID, event.NextCompetitorID)
+	case EventShotFired:
+		switch event.Shot {
+		case ShotHit:
+			return fmt.Sprintf("The competitor(%d) fired at the target(%d): hit", event.CompetitorID, event.Target)
+		case ShotMiss:
+			return fmt.Sprintf("The competitor(%d) fired at the target(%d): miss", event.CompetitorID, event.Target)
+		}
+		return fmt.Sprintf("The competitor(%d) fired a shot", event.CompetitorID)
+	case EventSpareLoaded:
+		return fmt.Sprintf("The competitor(%d) loaded a spare round", event.CompetitorID)
+	case EventJuryDisqualified:
+		if event.Comment == "" {
+			return fmt.Sprintf("The competitor(%d) was disqualified by the jury", event.CompetitorID)
+		}
+		return fmt.Sprintf("The competitor(%d) was disqualified by the jury: %s", event.CompetitorID, event.Comment)
+	case EventTimePenalty:
+		if event.Comment == "" {
+			return fmt.Sprintf("The competitor(%d) received a time penalty of %s", event.CompetitorID, event.Penalty)
+		}
+		return fmt.Sprintf("The competitor(%d) received a time penalty of %s: %s", event.CompetitorID, event.Penalty, event.Comment)
+	case EventSyncPulse:
+		return fmt.Sprintf("Timing sync pulse %s", event.ExtraParamsStr)
 	case EventDisqualified:
+		if event.Comment != "" {
+			return fmt.Sprintf("The competitor(%d) is disqualified: %s", event.CompetitorID, event.Comment)
+		}
 		return fmt.Sprintf("The competitor(%d) is disqualified", event.CompetitorID)
 	case EventFinished:
 		return fmt.Sprintf("The competitor(%d) has finished", event.CompetitorID)
+	case EventLapped:
+		return fmt.Sprintf("The competitor(%d) was lapped and pulled from the course at rank %s", event.CompetitorID, event.ExtraParamsStr)
 	default:
 		return fmt.Sprintf("Unknown event %d for competitor %d with params '%s'", event.ID, event.CompetitorID, event.ExtraParamsStr)
 	}
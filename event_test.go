@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
@@ -22,6 +23,10 @@ func TestLoadEvents(t *testing.T) {
 [09:49:31.659] 5 1 1
 [09:49:33.123] 6 1 2
 [09:59:05.321] 11 1 Lost in the forest
+[09:59:06.000] 13 1 3 miss
+[09:59:07.000] 13 1
+[09:59:08.000] 16 1 30s Missed penalty loop
+[09:59:09.000] 15 1 Unsporting behaviour
 `
 	validEventsPath := filepath.Join(tempDir, "valid_events.txt")
 	if err := os.WriteFile(validEventsPath, []byte(strings.TrimSpace(validEventsContent)), 0644); err != nil {
@@ -42,17 +47,18 @@ this is not a valid event
 		name          string
 		filePath      string
 		wantNumEvents int
+		wantDiags     int
 		wantErr       bool
 		checkEvents   func(t *testing.T, events []Event)
 	}{
 		{
 			name:          "ValidEvents",
 			filePath:      validEventsPath,
-			wantNumEvents: 5,
+			wantNumEvents: 9,
 			wantErr:       false,
 			checkEvents: func(t *testing.T, events []Event) {
-				if len(events) != 5 {
-					t.Fatalf("Expected 5 events, got %d", len(events))
+				if len(events) != 9 {
+					t.Fatalf("Expected 9 events, got %d", len(events))
 				}
 				ev0 := events[0]
 				if ev0.ID != EventRegistered || ev0.CompetitorID != 1 || !ev0.Timestamp.Equal(testTime(9, 5, 59, 867)) {
@@ -70,6 +76,22 @@ this is not a valid event
 				if ev4.ID != EventCannotContinue || ev4.CompetitorID != 1 || ev4.Comment != "Lost in the forest" {
 					t.Errorf("Event 4 (CannotContinue) mismatch: got %+v", ev4)
 				}
+				ev5 := events[5]
+				if ev5.ID != EventShotFired || ev5.Target != 3 || ev5.Shot != ShotMiss {
+					t.Errorf("Event 5 (ShotFired) mismatch: got %+v", ev5)
+				}
+				ev6 := events[6]
+				if ev6.ID != EventShotFired || ev6.Target != 0 || ev6.Shot != ShotUnknown {
+					t.Errorf("Event 6 (ShotFired without result) mismatch: got %+v", ev6)
+				}
+				ev7 := events[7]
+				if ev7.ID != EventTimePenalty || ev7.Penalty != 30*time.Second || ev7.Comment != "Missed penalty loop" {
+					t.Errorf("Event 7 (TimePenalty) mismatch: got %+v", ev7)
+				}
+				ev8 := events[8]
+				if ev8.ID != EventJuryDisqualified || ev8.Comment != "Unsporting behaviour" {
+					t.Errorf("Event 8 (JuryDisqualified) mismatch: got %+v", ev8)
+				}
 			},
 		},
 		{
@@ -82,13 +104,14 @@ this is not a valid event
 			name:          "EventsWithInvalidLine",
 			filePath:      invalidEventsPath,
 			wantNumEvents: 2,
+			wantDiags:     1,
 			wantErr:       false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotEvents, err := LoadEvents(tt.filePath)
+			gotEvents, diagnostics, err := LoadEvents(tt.filePath)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("LoadEvents() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -96,6 +119,9 @@ this is not a valid event
 			if len(gotEvents) != tt.wantNumEvents {
 				t.Errorf("LoadEvents() got %d events, want %d", len(gotEvents), tt.wantNumEvents)
 			}
+			if len(diagnostics) != tt.wantDiags {
+				t.Errorf("LoadEvents() got %d diagnostics, want %d", len(diagnostics), tt.wantDiags)
+			}
 			if tt.checkEvents != nil {
 				tt.checkEvents(t, gotEvents)
 			}
@@ -103,6 +129,88 @@ this is not a valid event
 	}
 }
 
+func TestParseEventLine_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantKind   DiagnosticKind
+		wantColumn int
+	}{
+		{name: "Malformed", raw: "this is not a valid event", wantKind: DiagnosticMalformedLine, wantColumn: 1},
+		{name: "MalformedIndented", raw: "  [09:00:00] 1 1", wantKind: DiagnosticMalformedLine, wantColumn: 3},
+		{name: "InvalidTimestamp", raw: "[09:61:00.000] 1 1", wantKind: DiagnosticInvalidTimestamp, wantColumn: 2},
+		{name: "InvalidCompetitorID", raw: "[09:00:00.000] 1 99999999999999999999", wantKind: DiagnosticInvalidCompetitorID, wantColumn: 18},
+		{name: "InvalidParams", raw: "[09:00:00.000] 5 1 first", wantKind: DiagnosticInvalidParams, wantColumn: 20},
+		{name: "InvalidParamsIndented", raw: "\t[09:00:00.000] 16 1 soon", wantKind: DiagnosticInvalidParams, wantColumn: 22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diagnostic, ok := ParseEventLine(tt.raw, SourcePos{File: "events", Line: 7, Offset: 100})
+			if ok || diagnostic == nil {
+				t.Fatalf("ParseEventLine() expected a diagnostic, got ok=%v", ok)
+			}
+			if diagnostic.Kind != tt.wantKind || diagnostic.Column != tt.wantColumn || diagnostic.Line != 7 || diagnostic.Raw != tt.raw ||
+				diagnostic.File != "events" || diagnostic.Offset != 100+int64(tt.wantColumn-1) {
+				t.Errorf("ParseEventLine() diagnostic = %+v, want kind %s at column %d", *diagnostic, tt.wantKind, tt.wantColumn)
+			}
+		})
+	}
+
+	if _, diagnostic, ok := ParseEventLine("   ", SourcePos{Line: 1}); ok || diagnostic != nil {
+		t.Errorf("Blank line should yield neither an event nor a diagnostic")
+	}
+}
+
+func TestLoadEvents_SourcePositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	content := "[09:00:00.000] 1 1\r\n\n  [09:00:01.000] 1 2\nbad line\n[09:00:02.000] 1 3"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, diagnostics, err := LoadEvents(path)
+	if err != nil {
+		t.Fatalf("LoadEvents() error = %v", err)
+	}
+	want := []SourcePos{
+		{File: path, Line: 1, Offset: 0},
+		{File: path, Line: 3, Offset: 21},
+		{File: path, Line: 5, Offset: 51},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(events))
+	}
+	for i, event := range events {
+		if event.Source != want[i] {
+			t.Errorf("Event %d source: got %+v, want %+v", i, event.Source, want[i])
+		}
+		if got := content[event.Source.Offset:]; !strings.HasPrefix(strings.TrimSpace(got), event.Line) {
+			t.Errorf("Event %d offset %d does not point at its line", i, event.Source.Offset)
+		}
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Line != 4 || diagnostics[0].Offset != 42 {
+		t.Errorf("Unexpected diagnostics: %+v", diagnostics)
+	}
+}
+
+func TestWriteDiagnostics(t *testing.T) {
+	var buf strings.Builder
+	err := WriteDiagnostics(&buf, []Diagnostic{{
+		File: "events", Line: 3, Column: 20, Raw: "[09:00:00.000] 5 1 first",
+		Kind: DiagnosticInvalidParams, Message: "invalid firing range",
+	}})
+	if err != nil {
+		t.Fatalf("WriteDiagnostics() error = %v", err)
+	}
+	want := "events:3:20: error: invalid firing range [invalid-params]\n" +
+		"    [09:00:00.000] 5 1 first\n" +
+		"                       ^\n"
+	if buf.String() != want {
+		t.Errorf("WriteDiagnostics() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
 func TestGetEventDescription(t *testing.T) {
 	regTime, _ := ParseTime("10:00:00.000")
 	event := Event{Timestamp: regTime, ID: EventRegistered, CompetitorID: 101}
@@ -121,3 +229,49 @@ func TestGetEventDescription(t *testing.T) {
 		t.Errorf("GetEventDescription() for EventStartTimeSet: got '%s', want '%s'", desc2, expectedDesc2)
 	}
 }
+
+func FuzzLoadEvents(f *testing.F) {
+	data, err := os.ReadFile(filepath.Join("input", "events"))
+	if err != nil {
+		f.Fatalf("Failed to read sample events: %v", err)
+	}
+	f.Add(data)
+	f.Add([]byte("[10:00:00.000] 13 1 3 hit\r\n\n  [10:00:01.000] 16 1 30s Short cut\n[bad"))
+	var binary bytes.Buffer
+	if err := WriteBinaryEvents(&binary, []Event{{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1}}, map[string]string{"race": "fuzz"}); err != nil {
+		f.Fatalf("WriteBinaryEvents() error = %v", err)
+	}
+	f.Add(binary.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		path := filepath.Join(t.TempDir(), "events")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write events: %v", err)
+		}
+		events, diagnostics, err := LoadEvents(path)
+		if err != nil {
+			if IsBinaryLog(data) {
+				return
+			}
+			t.Fatalf("LoadEvents() error = %v", err)
+		}
+
+		for i, event := range events {
+			if i > 0 && event.Source.Line <= events[i-1].Source.Line {
+				t.Fatalf("Event %d source line %d does not follow %d", i, event.Source.Line, events[i-1].Source.Line)
+			}
+			if event.Source.Offset < 0 || event.Source.Offset >= int64(len(data)) {
+				t.Fatalf("Event %d source offset %d is outside the file of %d bytes", i, event.Source.Offset, len(data))
+			}
+			reparsed, _, ok := ParseEventLine(event.Line, event.Source)
+			if !ok || reparsed.ID != event.ID || reparsed.CompetitorID != event.CompetitorID || !reparsed.Timestamp.Equal(event.Timestamp) {
+				t.Fatalf("Event %d line %q does not parse back to the event", i, event.Line)
+			}
+		}
+		for _, d := range diagnostics {
+			if !d.IsError() || d.Line < 1 || d.Column < 1 || d.Offset < 0 || d.Offset > int64(len(data)) {
+				t.Fatalf("Diagnostic %+v is out of range for a file of %d bytes", d, len(data))
+			}
+		}
+	})
+}
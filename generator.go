@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"time"
+)
+
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stdDev"`
+}
+
+func (d Distribution) Sample(rng *rand.Rand) float64 {
+	return d.Mean + rng.NormFloat64()*d.StdDev
+}
+
+// RaceProfile describes the field of a synthetic race. Ski speed is in m/s,
+// shooting accuracy is the per-shot hit probability of a competitor and range
+// time is the number of seconds spent on a firing line.
+type RaceProfile struct {
+	SkiSpeed          Distribution `json:"skiSpeed"`
+	ShootingAccuracy  Distribution `json:"shootingAccuracy"`
+	RangeTime         Distribution `json:"rangeTime"`
+	DNFProbability    float64      `json:"dnfProbability"`
+	NoShowProbability float64      `json:"noShowProbability"`
+}
+
+func DefaultRaceProfile() RaceProfile {
+	return RaceProfile{
+		SkiSpeed:          Distribution{Mean: 6.0, StdDev: 0.4},
+		ShootingAccuracy:  Distribution{Mean: 0.85, StdDev: 0.07},
+		RangeTime:         Distribution{Mean: 30, StdDev: 5},
+		DNFProbability:    0.03,
+		NoShowProbability: 0.02,
+	}
+}
+
+func LoadRaceProfile(filePath string) (RaceProfile, error) {
+	profile := DefaultRaceProfile()
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return profile, fmt.Errorf("failed to read race profile '%s': %w", filePath, err)
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return profile, fmt.Errorf("failed to unmarshal race profile JSON from '%s': %w", filePath, err)
+	}
+	return profile, nil
+}
+
+var dnfReasons = []string{"Lost in the forest", "Broken ski", "Injured", "Illness", "Broken pole"}
+
+type raceGenerator struct {
+	cfg     *Config
+	profile RaceProfile
+	rng     *rand.Rand
+	events  []Event
+}
+
+func (g *raceGenerator) emit(at time.Time, id EventID, competitorID int) *Event {
+	g.events = append(g.events, Event{
+		Timestamp:    at.Truncate(time.Millisecond),
+		ID:           id,
+		CompetitorID: competitorID,
+	})
+	return &g.events[len(g.events)-1]
+}
+
+func (g *raceGenerator) seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func (g *raceGenerator) uniform(from, to float64) time.Duration {
+	return g.seconds(from + g.rng.Float64()*(to-from))
+}
+
+// GenerateRace produces a chronologically ordered events log for a synthetic
+// race with the given number of competitors. The same seed always produces the
+// same log.
+func GenerateRace(cfg *Config, competitors int, profile RaceProfile, seed uint64) []Event {
+	g := &raceGenerator{
+		cfg:     cfg,
+		profile: profile,
+		rng:     rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+	}
+
+	registrationStart := cfg.StartTime.Add(-45 * time.Minute)
+	drawTime := cfg.StartTime.Add(-30 * time.Minute)
+	for id := 1; id <= competitors; id++ {
+		g.emit(registrationStart.Add(g.uniform(0, 600)), EventRegistered, id)
+		draw := g.emit(drawTime.Add(time.Duration(id)*time.Second), EventStartTimeSet, id)
+		draw.ScheduledStartTime = cfg.StartTime.Add(time.Duration(id-1) * cfg.StartDelta)
+	}
+
+	for id := 1; id <= competitors; id++ {
+		g.generateCompetitor(id, cfg.StartTime.Add(time.Duration(id-1)*cfg.StartDelta))
+	}
+
+	sort.SliceStable(g.events, func(i, j int) bool {
+		return g.events[i].Timestamp.Before(g.events[j].Timestamp)
+	})
+	return g.events
+}
+
+func (g *raceGenerator) generateCompetitor(id int, scheduled time.Time) {
+	if g.rng.Float64() < g.profile.NoShowProbability {
+		return
+	}
+
+	speed := math.Max(g.profile.SkiSpeed.Sample(g.rng), 1)
+	accuracy := math.Min(math.Max(g.profile.ShootingAccuracy.Sample(g.rng), 0), 1)
+
+	totalStages := g.cfg.Laps * g.cfg.FiringLines
+	dnfStage := -1
+	if g.rng.Float64() < g.profile.DNFProbability {
+		dnfStage = g.rng.IntN(totalStages + g.cfg.Laps)
+	}
+	stage := 0
+	dnf := func(at time.Time) bool {
+		if stage != dnfStage {
+			stage++
+			return false
+		}
+		event := g.emit(at, EventCannotContinue, id)
+		event.Comment = dnfReasons[g.rng.IntN(len(dnfReasons))]
+		return true
+	}
+
+	g.emit(scheduled.Add(-g.uniform(10, 60)), EventOnStartLine, id)
+	now := scheduled.Add(g.uniform(0, 2))
+	g.emit(now, EventStarted, id)
+
+	segments := g.cfg.FiringLines + 1
+	for lap := 1; lap <= g.cfg.Laps; lap++ {
+		lapSpeed := speed * (1 + g.rng.NormFloat64()*0.02)
+		segment := g.seconds(float64(g.cfg.LapLen) / lapSpeed / float64(segments))
+
+		for line := 1; line <= g.cfg.FiringLines; line++ {
+			now = now.Add(segment)
+			if dnf(now) {
+				return
+			}
+			now = g.generateStage(id, line, now, accuracy, lapSpeed)
+		}
+
+		now = now.Add(segment)
+		if dnf(now) {
+			return
+		}
+		g.emit(now, EventEndedMainLap, id)
+	}
+}
+
+func (g *raceGenerator) generateStage(id, line int, now time.Time, accuracy, speed float64) time.Time {
+	arrival := g.emit(now, EventOnFiringRange, id)
+	arrival.FiringRange = line
+
+	rangeTime := g.seconds(math.Max(g.profile.RangeTime.Sample(g.rng), 10))
+	shotInterval := rangeTime / (TargetsPerStage + 2)
+	hits := 0
+	for target := 1; target <= TargetsPerStage; target++ {
+		if g.rng.Float64() < accuracy {
+			hit := g.emit(now.Add(time.Duration(target)*shotInterval), EventTargetHit, id)
+			hit.Target = target
+			hits++
+		}
+	}
+	now = now.Add(rangeTime)
+	g.emit(now, EventLeftFiringRange, id)
+
+	if penalties := TargetsPerStage - hits; penalties > 0 && g.cfg.PenaltyLen > 0 {
+		now = now.Add(g.uniform(5, 15))
+		g.emit(now, EventEnteredPenaltyLaps, id)
+		now = now.Add(g.seconds(float64(penalties*g.cfg.PenaltyLen) / (speed * 0.9)))
+		g.emit(now, EventLeftPenaltyLaps, id)
+	}
+	return now
+}
+
+func WriteEvents(w io.Writer, events []Event) error {
+	writer := bufio.NewWriter(w)
+	for _, event := range events {
+		if _, err := fmt.Fprintln(writer, FormatEventLine(event)); err != nil {
+			return fmt.Errorf("failed to write event for competitor %d: %w", event.CompetitorID, err)
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateRace_RoundTripsThroughLoadEvents(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Laps = 2
+	cfg.FiringLines = 2
+	profile := DefaultRaceProfile()
+	profile.DNFProbability = 0
+	profile.NoShowProbability = 0
+
+	events := GenerateRace(cfg, 10, profile, 42)
+
+	var buf bytes.Buffer
+	if err := WriteEvents(&buf, events); err != nil {
+		t.Fatalf("WriteEvents() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "events")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write generated events: %v", err)
+	}
+
+	loaded, diagnostics, err := LoadEvents(path)
+	if err != nil {
+		t.Fatalf("LoadEvents() error = %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Fatalf("LoadEvents() reported diagnostics for generated events: %v", diagnostics)
+	}
+	if len(loaded) != len(events) {
+		t.Fatalf("LoadEvents() got %d events, want %d", len(loaded), len(events))
+	}
+	for i := 1; i < len(loaded); i++ {
+		if loaded[i].Timestamp.Before(loaded[i-1].Timestamp) {
+			t.Fatalf("Events out of order at line %d", i+1)
+		}
+	}
+
+	sim := NewSimulation(cfg)
+	sim.Run(loaded)
+	sim.FinalizeResults()
+
+	if len(sim.Competitors) != 10 {
+		t.Fatalf("Expected 10 competitors, got %d", len(sim.Competitors))
+	}
+	for id, c := range sim.Competitors {
+		if c.Status != StatusCompleted {
+			t.Errorf("Competitor %d status: got %s, want %s", id, c.Status, StatusCompleted)
+		}
+		if len(c.LapsData) != cfg.Laps {
+			t.Errorf("Competitor %d laps: got %d, want %d", id, len(c.LapsData), cfg.Laps)
+		}
+		if c.TotalShots != cfg.Laps*cfg.FiringLines*TargetsPerStage {
+			t.Errorf("Competitor %d shots: got %d", id, c.TotalShots)
+		}
+		want := cfg.StartTime.Add(cfg.StartDelta * time.Duration(id-1))
+		if !c.ScheduledStartTime.Equal(want) {
+			t.Errorf("Competitor %d scheduled start: got %v, want %v", id, c.ScheduledStartTime, want)
+		}
+	}
+}
+
+func TestGenerateRace_SeedIsReproducible(t *testing.T) {
+	cfg := createTestConfig()
+	profile := DefaultRaceProfile()
+	profile.DNFProbability = 0.3
+	profile.NoShowProbability = 0.2
+
+	var first, second, other bytes.Buffer
+	WriteEvents(&first, GenerateRace(cfg, 20, profile, 7))
+	WriteEvents(&second, GenerateRace(cfg, 20, profile, 7))
+	WriteEvents(&other, GenerateRace(cfg, 20, profile, 8))
+
+	if first.String() != second.String() {
+		t.Errorf("Same seed produced different events")
+	}
+	if first.String() == other.String() {
+		t.Errorf("Different seeds produced identical events")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type RankedCompetitor struct {
+	Competitor *Competitor
+	Overall    RankEntry
+	Group      RankEntry
+}
+
+type ResultGroup struct {
+	Key     string
+	Values  []string
+	Entries []RankedCompetitor
+}
+
+// ParseGroupBy splits a comma-separated list of competitor attributes,
+// trimming the spaces around each one.
+func ParseGroupBy(s string) ([]string, error) {
+	keys := strings.Split(s, ",")
+	for i, key := range keys {
+		keys[i] = strings.TrimSpace(key)
+		if keys[i] == "" {
+			return nil, fmt.Errorf("empty attribute in group-by list '%s'", s)
+		}
+	}
+	return keys, nil
+}
+
+// GroupResults splits an overall ranking into groups keyed by the given
+// competitor attributes. Each entry keeps its overall rank and gets a rank
+// within its group.
+func GroupResults(ranking []RankEntry, keys []string, config *Config) []ResultGroup {
+	groupsByKey := make(map[string]*ResultGroup)
+	var order []string
+
+	for _, overall := range ranking {
+		c := overall.Competitor
+		values := make([]string, len(keys))
+		for i, key := range keys {
+			values[i] = c.Attribute(key)
+		}
+		groupKey := strings.Join(values, " ")
+
+		group, ok := groupsByKey[groupKey]
+		if !ok {
+			group = &ResultGroup{Key: groupKey, Values: values}
+			groupsByKey[groupKey] = group
+			order = append(order, groupKey)
+		}
+		group.Entries = append(group.Entries, RankedCompetitor{Competitor: c, Overall: overall})
+	}
+
+	sort.Strings(order)
+	groups := make([]ResultGroup, 0, len(order))
+	for _, key := range order {
+		group := groupsByKey[key]
+		group.Sort(config.Timing())
+		groups = append(groups, *group)
+	}
+	return groups
+}
+
+func (g *ResultGroup) Sort(timing TimingPolicy) {
+	sort.SliceStable(g.Entries, func(i, j int) bool {
+		return competitorLess(g.Entries[i].Competitor, g.Entries[j].Competitor, timing)
+	})
+
+	sorted := make([]*Competitor, len(g.Entries))
+	for i, entry := range g.Entries {
+		sorted[i] = entry.Competitor
+	}
+	for i, groupEntry := range RankSorted(sorted, timing) {
+		g.Entries[i].Group = groupEntry
+	}
+}
+
+func (g *ResultGroup) FileName() string {
+	name := strings.ToLower(strings.Join(g.Values, "_"))
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" {
+		name = "overall"
+	}
+	return name + ".csv"
+}
+
+func WriteGroupCSV(w io.Writer, group ResultGroup, config *Config) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"Rank", "Overall", "ID", "Result/Status", "Behind", "Laps", "Penalty", "Shooting"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header for group '%s': %w", group.Key, err)
+	}
+
+	for _, entry := range group.Entries {
+		c := entry.Competitor
+		record := []string{
+			entry.Group.RankString(),
+			formatRank(entry.Overall.Rank, entry.Overall.Tied),
+			strconv.Itoa(c.ID),
+			c.GetOverallStatusForReport(config),
+			entry.Group.BehindString(),
+			c.FormatLapResults(config),
+			formatPenaltyStats(c, config),
+			c.FinalShootingString(),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record for competitor %d: %w", c.ID, err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func finishedCompetitor(id int, raceTime time.Duration, attributes map[string]string) *Competitor {
+	c := NewCompetitor(id)
+	c.Status = StatusCompleted
+	c.ActualStartTime = testTime(10, 0, 0, 0)
+	c.FinishTime = c.ActualStartTime.Add(raceTime)
+	c.Attributes = attributes
+	return c
+}
+
+func TestGroupResults(t *testing.T) {
+	men := map[string]string{"gender": "Men", "category": "U19"}
+	women := map[string]string{"gender": "Women", "category": "Senior"}
+
+	dnf := NewCompetitor(5)
+	dnf.Status = StatusNotFinished
+	dnf.Attributes = men
+
+	competitors := map[int]*Competitor{
+		1: finishedCompetitor(1, 30*time.Minute, men),
+		2: finishedCompetitor(2, 25*time.Minute, women),
+		3: finishedCompetitor(3, 28*time.Minute, men),
+		4: finishedCompetitor(4, 27*time.Minute, nil),
+		5: dnf,
+	}
+
+	cfg := createTestConfig()
+	groups := GroupResults(RankCompetitors(competitors, cfg), []string{"gender", "category"}, cfg)
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d", len(groups))
+	}
+
+	tests := []struct {
+		key         string
+		wantIDs     []int
+		wantGroup   []int
+		wantOverall []int
+	}{
+		{"Men U19", []int{3, 1, 5}, []int{1, 2, 0}, []int{3, 4, 0}},
+		{"Unassigned Unassigned", []int{4}, []int{1}, []int{2}},
+		{"Women Senior", []int{2}, []int{1}, []int{1}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			group := groups[i]
+			if group.Key != tt.key {
+				t.Fatalf("Group key: got '%s', want '%s'", group.Key, tt.key)
+			}
+			if len(group.Entries) != len(tt.wantIDs) {
+				t.Fatalf("Group entries: got %d, want %d", len(group.Entries), len(tt.wantIDs))
+			}
+			for j, entry := range group.Entries {
+				if entry.Competitor.ID != tt.wantIDs[j] {
+					t.Errorf("Entry %d ID: got %d, want %d", j, entry.Competitor.ID, tt.wantIDs[j])
+				}
+				if entry.Group.Rank != tt.wantGroup[j] {
+					t.Errorf("Entry %d group rank: got %d, want %d", j, entry.Group.Rank, tt.wantGroup[j])
+				}
+				if entry.Overall.Rank != tt.wantOverall[j] {
+					t.Errorf("Entry %d overall rank: got %d, want %d", j, entry.Overall.Rank, tt.wantOverall[j])
+				}
+			}
+		})
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Single", input: "gender", want: []string{"gender"}},
+		{name: "Spaces", input: "gender, category ", want: []string{"gender", "category"}},
+		{name: "EmptyEntry", input: "gender,,category", wantErr: true},
+		{name: "TrailingComma", input: "gender, ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGroupBy(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGroupBy(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGroupBy(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteGroupCSV(t *testing.T) {
+	cfg := createTestConfig()
+	men := map[string]string{"gender": "Men"}
+	competitors := map[int]*Competitor{
+		1: finishedCompetitor(1, 30*time.Minute, men),
+	}
+	groups := GroupResults(RankCompetitors(competitors, cfg), []string{"gender"}, cfg)
+
+	var buf bytes.Buffer
+	if err := WriteGroupCSV(&buf, groups[0], cfg); err != nil {
+		t.Fatalf("WriteGroupCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected header and 1 record, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[1], "1,1,1,00:30:00.0,,") {
+		t.Errorf("Unexpected CSV record: %s", lines[1])
+	}
+	if groups[0].FileName() != "men.csv" {
+		t.Errorf("FileName() = %s, want men.csv", groups[0].FileName())
+	}
+}
+
+func TestSimulation_ApplyRosterOnlyToCompetitorsWithEvents(t *testing.T) {
+	sim := NewSimulation(createTestConfig())
+	sim.Run([]Event{{Timestamp: testTime(9, 50, 0, 0), ID: EventRegistered, CompetitorID: 1}})
+	sim.ApplyRoster(Roster{
+		1:  {"gender": "Men"},
+		2:  {"gender": "Women"},
+		99: {"gender": "Men"},
+	})
+	sim.Run([]Event{{Timestamp: testTime(9, 51, 0, 0), ID: EventRegistered, CompetitorID: 2}})
+
+	if len(sim.Competitors) != 2 {
+		t.Fatalf("Got %d competitors, want 2", len(sim.Competitors))
+	}
+	for id, want := range map[int]string{1: "Men", 2: "Women"} {
+		if got := sim.Competitors[id].Attributes["gender"]; got != want {
+			t.Errorf("Competitor %d gender = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const historyDateLayout = "2006-01-02"
+
+const (
+	PositionProne    = "prone"
+	PositionStanding = "standing"
+)
+
+type StageHistory struct {
+	Stage    int    `json:"stage"`
+	Position string `json:"position"`
+	Hits     int    `json:"hits"`
+	Shots    int    `json:"shots"`
+}
+
+type LapHistory struct {
+	Lap          int     `json:"lap"`
+	DurationMs   int64   `json:"durationMs"`
+	AverageSpeed float64 `json:"averageSpeed"`
+}
+
+type CompetitorHistory struct {
+	CompetitorID int               `json:"competitorId"`
+	Athlete      string            `json:"athlete"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Status       CompetitorStatus  `json:"status"`
+	Rank         int               `json:"rank,omitempty"`
+	RaceTimeMs   int64             `json:"raceTimeMs,omitempty"`
+	OfficialTime string            `json:"officialTime,omitempty"`
+	Laps         []LapHistory      `json:"laps"`
+	Stages       []StageHistory    `json:"stages"`
+}
+
+type RaceHistory struct {
+	RaceID      string              `json:"raceId"`
+	Date        string              `json:"date"`
+	Mode        string              `json:"mode"`
+	Discipline  string              `json:"discipline,omitempty"`
+	Laps        int                 `json:"laps"`
+	LapLen      int                 `json:"lapLen"`
+	Competitors []CompetitorHistory `json:"competitors"`
+}
+
+type AthleteStats struct {
+	Athlete          string
+	Races            int
+	Starts           int
+	Finishes         int
+	DNFs             int
+	DNFRate          float64
+	ProneHits        int
+	ProneShots       int
+	StandingHits     int
+	StandingShots    int
+	ProneAccuracy    float64
+	StandingAccuracy float64
+	AverageSpeed     float64
+}
+
+// AthleteKey identifies a competitor across races. Bib numbers change from
+// race to race, so the roster "athlete" attribute is preferred when present.
+func AthleteKey(c *Competitor) string {
+	if athlete := c.Attributes["athlete"]; athlete != "" {
+		return athlete
+	}
+	return strconv.Itoa(c.ID)
+}
+
+// ShootingPosition returns the position of the n-th stage of a race, counted
+// from 1. Positions cycle through Config.ShootingPositions, alternating prone
+// and standing when none are configured.
+func (c *Config) ShootingPosition(stage int) string {
+	positions := c.ShootingPositions
+	if len(positions) == 0 {
+		positions = []string{PositionProne, PositionStanding}
+	}
+	return positions[(stage-1)%len(positions)]
+}
+
+func NewRaceHistory(raceID string, date time.Time, sim *Simulation) RaceHistory {
+	race := RaceHistory{
+		RaceID:     raceID,
+		Date:       date.Format(historyDateLayout),
+		Mode:       sim.Config.Mode,
+		Discipline: sim.Config.Discipline,
+		Laps:       sim.Config.Laps,
+		LapLen:     sim.Config.LapLen,
+	}
+
+	for _, entry := range RankCompetitors(sim.Competitors, sim.Config) {
+		c := entry.Competitor
+		record := CompetitorHistory{
+			CompetitorID: c.ID,
+			Athlete:      AthleteKey(c),
+			Attributes:   c.Attributes,
+			Status:       c.Status,
+			Rank:         entry.Rank,
+			Laps:         make([]LapHistory, 0, len(c.LapsData)),
+			Stages:       make([]StageHistory, 0),
+		}
+		if hasFinished(c) {
+			record.RaceTimeMs = RaceTime(c).Milliseconds()
+			record.OfficialTime = sim.Config.Timing().Format(entry.RaceTime)
+		}
+
+		stage := 0
+		for _, lap := range c.LapsData {
+			if lap.LapDuration > 0 {
+				record.Laps = append(record.Laps, LapHistory{
+					Lap:          lap.LapNumber,
+					DurationMs:   lap.LapDuration.Milliseconds(),
+					AverageSpeed: lap.AverageSpeed,
+				})
+			}
+			for _, sr := range lap.ShootingData {
+				stage++
+				record.Stages = append(record.Stages, StageHistory{
+					Stage:    stage,
+					Position: sim.Config.ShootingPosition(stage),
+					Hits:     sr.Hits,
+					Shots:    sr.Shots,
+				})
+			}
+		}
+		race.Competitors = append(race.Competitors, record)
+	}
+	return race
+}
+
+func LoadRaceHistory(filePath string) (RaceHistory, error) {
+	var race RaceHistory
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return race, fmt.Errorf("failed to read race file '%s': %w", filePath, err)
+	}
+	if err := json.Unmarshal(data, &race); err != nil {
+		return race, fmt.Errorf("failed to unmarshal race file '%s': %w", filePath, err)
+	}
+	return race, nil
+}
+
+// HistoryStore keeps finished races as one JSON file per race in a directory.
+type HistoryStore struct {
+	Dir string
+}
+
+func OpenHistoryStore(dir string) (*HistoryStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history store '%s': %w", dir, err)
+	}
+	return &HistoryStore{Dir: dir}, nil
+}
+
+func (h *HistoryStore) racePath(raceID string) string {
+	return filepath.Join(h.Dir, raceID+".json")
+}
+
+// RecordRace stores the finalized simulation under the race ID, replacing an
+// earlier record with the same ID.
+func (h *HistoryStore) RecordRace(raceID string, date time.Time, sim *Simulation) error {
+	if raceID == "" || strings.ContainsAny(raceID, `/\`) {
+		return fmt.Errorf("invalid race ID '%s'", raceID)
+	}
+	data, err := json.MarshalIndent(NewRaceHistory(raceID, date, sim), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal race '%s': %w", raceID, err)
+	}
+	if err := os.WriteFile(h.racePath(raceID), data, 0644); err != nil {
+		return fmt.Errorf("failed to write race '%s': %w", raceID, err)
+	}
+	return nil
+}
+
+// Races returns the stored races dated within [from, to], ordered by date. A
+// zero bound is open.
+func (h *HistoryStore) Races(from, to time.Time) ([]RaceHistory, error) {
+	paths, err := filepath.Glob(filepath.Join(h.Dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list history store '%s': %w", h.Dir, err)
+	}
+
+	var races []RaceHistory
+	for _, path := range paths {
+		race, err := LoadRaceHistory(path)
+		if err != nil {
+			return nil, err
+		}
+		date, err := time.Parse(historyDateLayout, race.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date '%s' in race file '%s': %w", race.Date, path, err)
+		}
+		if (!from.IsZero() && date.Before(from)) || (!to.IsZero() && date.After(to)) {
+			continue
+		}
+		races = append(races, race)
+	}
+
+	sort.Slice(races, func(i, j int) bool {
+		if races[i].Date != races[j].Date {
+			return races[i].Date < races[j].Date
+		}
+		return races[i].RaceID < races[j].RaceID
+	})
+	return races, nil
+}
+
+// SeasonStats aggregates every athlete's results over the races dated within
+// [from, to].
+func (h *HistoryStore) SeasonStats(from, to time.Time) ([]AthleteStats, error) {
+	races, err := h.Races(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return AggregateAthleteStats(races), nil
+}
+
+// AthleteStats returns the aggregated results of a single athlete.
+func (h *HistoryStore) AthleteStats(athlete string, from, to time.Time) (AthleteStats, error) {
+	stats, err := h.SeasonStats(from, to)
+	if err != nil {
+		return AthleteStats{}, err
+	}
+	for _, s := range stats {
+		if s.Athlete == athlete {
+			return s, nil
+		}
+	}
+	return AthleteStats{}, fmt.Errorf("no history for athlete '%s'", athlete)
+}
+
+func AggregateAthleteStats(races []RaceHistory) []AthleteStats {
+	byAthlete := make(map[string]*AthleteStats)
+	speedSums := make(map[string]float64)
+	speedLaps := make(map[string]int)
+
+	for _, race := range races {
+		for _, record := range race.Competitors {
+			stats, ok := byAthlete[record.Athlete]
+			if !ok {
+				stats = &AthleteStats{Athlete: record.Athlete}
+				byAthlete[record.Athlete] = stats
+			}
+			stats.Races++
+			switch record.Status {
+			case StatusNotStarted:
+			case StatusNotFinished:
+				stats.Starts++
+				stats.DNFs++
+			case StatusCompleted:
+				stats.Starts++
+				stats.Finishes++
+			default:
+				stats.Starts++
+			}
+			for _, lap := range record.Laps {
+				speedSums[record.Athlete] += lap.AverageSpeed
+				speedLaps[record.Athlete]++
+			}
+			for _, stage := range record.Stages {
+				switch stage.Position {
+				case PositionProne:
+					stats.ProneHits += stage.Hits
+					stats.ProneShots += stage.Shots
+				case PositionStanding:
+					stats.StandingHits += stage.Hits
+					stats.StandingShots += stage.Shots
+				}
+			}
+		}
+	}
+
+	result := make([]AthleteStats, 0, len(byAthlete))
+	for athlete, stats := range byAthlete {
+		if stats.Starts > 0 {
+			stats.DNFRate = float64(stats.DNFs) / float64(stats.Starts)
+		}
+		if stats.ProneShots > 0 {
+			stats.ProneAccuracy = float64(stats.ProneHits) / float64(stats.ProneShots)
+		}
+		if stats.StandingShots > 0 {
+			stats.StandingAccuracy = float64(stats.StandingHits) / float64(stats.StandingShots)
+		}
+		if speedLaps[athlete] > 0 {
+			stats.AverageSpeed = speedSums[athlete] / float64(speedLaps[athlete])
+		}
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Athlete < result[j].Athlete
+	})
+	return result
+}
+
+func WriteAthleteStats(w io.Writer, stats []AthleteStats) {
+	headerFormat := "%-15s %-6s %-6s %-9s %-6s %-16s %-16s %-10s\n"
+	fmt.Fprintf(w, headerFormat, "Athlete", "Races", "Starts", "Finishes", "DNF%", "Prone", "Standing", "Speed m/s")
+	for _, s := range stats {
+		fmt.Fprintf(w, headerFormat,
+			s.Athlete,
+			strconv.Itoa(s.Races),
+			strconv.Itoa(s.Starts),
+			strconv.Itoa(s.Finishes),
+			fmt.Sprintf("%.1f", s.DNFRate*100),
+			fmt.Sprintf("%d/%d %.1f%%", s.ProneHits, s.ProneShots, s.ProneAccuracy*100),
+			fmt.Sprintf("%d/%d %.1f%%", s.StandingHits, s.StandingShots, s.StandingAccuracy*100),
+			fmt.Sprintf("%.3f", s.AverageSpeed))
+	}
+}
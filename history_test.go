@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func historyTestSimulation(bib int, athlete string, dnf bool) *Simulation {
+	cfg := createTestConfig()
+	cfg.Laps = 2
+	sim := NewSimulation(cfg)
+	sim.ApplyRoster(Roster{bib: {"athlete": athlete}})
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: bib},
+		{Timestamp: testTime(10, 4, 0, 0), ID: EventOnFiringRange, CompetitorID: bib, FiringRange: 1},
+		{Timestamp: testTime(10, 4, 10, 0), ID: EventTargetHit, CompetitorID: bib, Target: 1},
+		{Timestamp: testTime(10, 4, 30, 0), ID: EventLeftFiringRange, CompetitorID: bib},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: bib},
+		{Timestamp: testTime(10, 14, 0, 0), ID: EventOnFiringRange, CompetitorID: bib, FiringRange: 1},
+	}
+	for target := 1; target <= 4; target++ {
+		events = append(events, Event{Timestamp: testTime(10, 14, target, 0), ID: EventTargetHit, CompetitorID: bib, Target: target})
+	}
+	events = append(events, Event{Timestamp: testTime(10, 14, 30, 0), ID: EventLeftFiringRange, CompetitorID: bib})
+	if dnf {
+		events = append(events, Event{Timestamp: testTime(10, 16, 0, 0), ID: EventCannotContinue, CompetitorID: bib, Comment: "Illness"})
+	} else {
+		events = append(events, Event{Timestamp: testTime(10, 20, 0, 0), ID: EventEndedMainLap, CompetitorID: bib})
+	}
+
+	sim.Run(events)
+	sim.FinalizeResults()
+	return sim
+}
+
+func TestHistoryStore_SeasonStats(t *testing.T) {
+	store, err := OpenHistoryStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenHistoryStore() error = %v", err)
+	}
+
+	races := []struct {
+		id   string
+		date time.Time
+		bib  int
+		dnf  bool
+	}{
+		{"oberhof-sprint", time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC), 7, false},
+		{"ruhpolding-individual", time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), 21, true},
+		{"antholz-sprint", time.Date(2026, 1, 22, 0, 0, 0, 0, time.UTC), 3, false},
+	}
+	for _, race := range races {
+		if err := store.RecordRace(race.id, race.date, historyTestSimulation(race.bib, "Boe", race.dnf)); err != nil {
+			t.Fatalf("RecordRace(%s) error = %v", race.id, err)
+		}
+	}
+
+	stats, err := store.AthleteStats("Boe", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("AthleteStats() error = %v", err)
+	}
+	if stats.Races != 3 || stats.Starts != 3 || stats.Finishes != 2 || stats.DNFs != 1 {
+		t.Errorf("Race counts mismatch: %+v", stats)
+	}
+	if math.Abs(stats.DNFRate-1.0/3.0) > 1e-9 {
+		t.Errorf("DNFRate: got %f, want 0.333", stats.DNFRate)
+	}
+	if stats.ProneHits != 3 || stats.ProneShots != 15 || stats.StandingHits != 12 || stats.StandingShots != 15 {
+		t.Errorf("Shooting by position mismatch: %+v", stats)
+	}
+	if math.Abs(stats.AverageSpeed-1000.0/600.0) > 1e-9 {
+		t.Errorf("AverageSpeed: got %f, want %f", stats.AverageSpeed, 1000.0/600.0)
+	}
+
+	january := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	filtered, err := store.AthleteStats("Boe", january, time.Time{})
+	if err != nil {
+		t.Fatalf("AthleteStats() with date range error = %v", err)
+	}
+	if filtered.Races != 2 {
+		t.Errorf("Filtered races: got %d, want 2", filtered.Races)
+	}
+
+	if _, err := store.AthleteStats("Fourcade", time.Time{}, time.Time{}); err == nil {
+		t.Errorf("Expected an error for an athlete without history")
+	}
+	if err := store.RecordRace("../escape", january, historyTestSimulation(1, "Boe", false)); err == nil {
+		t.Errorf("Expected an error for a race ID with a path separator")
+	}
+}
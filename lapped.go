@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PullsLapped reports whether lapped competitors are taken off the course,
+// which is the case in pursuit and mass start races.
+func (c *Config) PullsLapped() bool {
+	discipline := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(c.Discipline))
+	return discipline == "pursuit" || discipline == "massstart"
+}
+
+func isOnCourse(c *Competitor) bool {
+	return c.Status == StatusRacing || c.Status == StatusOnRange || c.Status == StatusInPenalty
+}
+
+// pullLapped takes every competitor the leader has gained a full lap on off
+// the course. A competitor is lapped once they have completed at least two
+// laps fewer than the leader. Pulled competitors get the rank they held at
+// that moment: the last place among those still in the race.
+func (s *Simulation) pullLapped(timestamp time.Time) {
+	leaderLaps := 0
+	inRace := 0
+	for _, c := range s.Competitors {
+		if isOnCourse(c) || c.Status == StatusCompleted {
+			leaderLaps = max(leaderLaps, lapsCompleted(c))
+			inRace++
+		}
+	}
+
+	var lapped []*Competitor
+	for _, c := range s.Competitors {
+		if isOnCourse(c) && lapsCompleted(c) <= leaderLaps-2 {
+			lapped = append(lapped, c)
+		}
+	}
+	if len(lapped) == 0 {
+		return
+	}
+
+	// The furthest behind is pulled into the last place.
+	sort.Slice(lapped, func(i, j int) bool {
+		l1, l2 := lapsCompleted(lapped[i]), lapsCompleted(lapped[j])
+		if l1 != l2 {
+			return l1 > l2
+		}
+		e1, e2 := lastLapEnd(lapped[i]), lastLapEnd(lapped[j])
+		if !e1.Equal(e2) {
+			return e1.Before(e2)
+		}
+		return lapped[i].ID < lapped[j].ID
+	})
+
+	rank := inRace - len(lapped)
+	for _, c := range lapped {
+		rank++
+		c.Status = StatusLapped
+		c.LappedRank = rank
+		lappedEvent := Event{
+			Timestamp:      timestamp,
+			ID:             EventLapped,
+			CompetitorID:   c.ID,
+			ExtraParamsStr: strconv.Itoa(rank),
+		}
+		c.GeneratedEvents = append(c.GeneratedEvents, lappedEvent)
+		s.logEvent(lappedEvent, true)
+	}
+}
+
+func lastLapEnd(c *Competitor) time.Time {
+	if n := lapsCompleted(c); n > 0 {
+		return c.LapsData[n-1].EndTime
+	}
+	return c.ActualStartTime
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulation_PullsLappedCompetitors(t *testing.T) {
+	lapEnd := func(m, id int) Event {
+		return Event{Timestamp: testTime(10, m, 0, 0), ID: EventEndedMainLap, CompetitorID: id}
+	}
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 3},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 4},
+		lapEnd(5, 1),
+		lapEnd(6, 2),
+		lapEnd(8, 3),
+		lapEnd(10, 1),
+		lapEnd(12, 2),
+		lapEnd(15, 1),
+		lapEnd(16, 3),
+		lapEnd(18, 2),
+	}
+
+	tests := []struct {
+		name       string
+		discipline string
+		wantStatus map[int]CompetitorStatus
+		wantOrder  []int
+		wantRanks  []string
+	}{
+		{
+			name:       "Pursuit",
+			discipline: "pursuit",
+			wantStatus: map[int]CompetitorStatus{1: StatusCompleted, 2: StatusCompleted, 3: StatusLapped, 4: StatusLapped},
+			wantOrder:  []int{1, 2, 3, 4},
+			wantRanks:  []string{"1", "2", "3", "4"},
+		},
+		{
+			name:       "Sprint",
+			discipline: "sprint",
+			wantStatus: map[int]CompetitorStatus{1: StatusCompleted, 2: StatusCompleted, 3: StatusRacing, 4: StatusRacing},
+			wantOrder:  []int{1, 2, 3, 4},
+			wantRanks:  []string{"1", "2", "Racing", "Racing"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.Laps = 3
+			cfg.Discipline = tt.discipline
+			sim := NewSimulation(cfg)
+			sim.Run(append([]Event(nil), events...))
+			sim.FinalizeResults()
+
+			for id, want := range tt.wantStatus {
+				if got := sim.Competitors[id].Status; got != want {
+					t.Errorf("Competitor %d status: got %s, want %s", id, got, want)
+				}
+			}
+
+			ranking := RankCompetitors(sim.Competitors, cfg)
+			for i, entry := range ranking {
+				if entry.Competitor.ID != tt.wantOrder[i] || entry.RankString() != tt.wantRanks[i] {
+					t.Errorf("Place %d: got competitor %d rank %s, want %d rank %s", i+1, entry.Competitor.ID, entry.RankString(), tt.wantOrder[i], tt.wantRanks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSimulation_LappedRankAtPull(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Laps = 4
+	cfg.Discipline = "mass start"
+	sim := NewSimulation(cfg)
+
+	sim.Run([]Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 3},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 9, 0, 0), ID: EventEndedMainLap, CompetitorID: 2},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 15, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 16, 0, 0), ID: EventEndedMainLap, CompetitorID: 3},
+		{Timestamp: testTime(10, 20, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+	})
+
+	pulledFirst := sim.Competitors[3]
+	if pulledFirst.Status != StatusLapped || pulledFirst.LappedRank != 3 {
+		t.Errorf("Competitor 3: got %s at rank %d, want Lapped at rank 3", pulledFirst.Status, pulledFirst.LappedRank)
+	}
+	if lapsCompleted(pulledFirst) != 0 {
+		t.Errorf("Competitor 3: lap events after being pulled should not count, got %d laps", lapsCompleted(pulledFirst))
+	}
+	pulledSecond := sim.Competitors[2]
+	if pulledSecond.Status != StatusLapped || pulledSecond.LappedRank != 2 {
+		t.Errorf("Competitor 2: got %s at rank %d, want Lapped at rank 2", pulledSecond.Status, pulledSecond.LappedRank)
+	}
+	if len(pulledSecond.GeneratedEvents) != 1 || pulledSecond.GeneratedEvents[0].ID != EventLapped {
+		t.Errorf("Competitor 2: expected a generated Lapped event, got %+v", pulledSecond.GeneratedEvents)
+	}
+}
+
+func TestSimulation_LappedCompetitorIgnoresCourseEvents(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Laps = 4
+	cfg.Discipline = "pursuit"
+	sim := NewSimulation(cfg)
+
+	sim.Run([]Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 11, 0, 0), ID: EventOnFiringRange, CompetitorID: 2, FiringRange: 1},
+		{Timestamp: testTime(10, 11, 10, 0), ID: EventTargetHit, CompetitorID: 2, Target: 1},
+		{Timestamp: testTime(10, 11, 30, 0), ID: EventLeftFiringRange, CompetitorID: 2},
+		{Timestamp: testTime(10, 12, 0, 0), ID: EventEnteredPenaltyLaps, CompetitorID: 2},
+		{Timestamp: testTime(10, 13, 0, 0), ID: EventLeftPenaltyLaps, CompetitorID: 2},
+		{Timestamp: testTime(10, 14, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 15, 0, 0), ID: EventTimePenalty, CompetitorID: 2, Penalty: time.Minute},
+	})
+
+	c := sim.Competitors[2]
+	if c.Status != StatusLapped {
+		t.Errorf("Status = %s, want %s", c.Status, StatusLapped)
+	}
+	if c.TotalHits != 0 || c.TotalShots != 0 || c.TotalPenaltiesServed != 0 {
+		t.Errorf("Course events after the pull counted: %d/%d shooting, %d penalty laps", c.TotalHits, c.TotalShots, c.TotalPenaltiesServed)
+	}
+	if c.CurrentLapNumber != 1 || !c.ActualStartTime.Equal(testTime(10, 0, 0, 0)) {
+		t.Errorf("Start after the pull restarted the competitor: lap %d, start %s", c.CurrentLapNumber, FormatTime(c.ActualStartTime))
+	}
+	if c.TimeAdjustment != time.Minute {
+		t.Errorf("TimeAdjustment = %v, want 1m0s", c.TimeAdjustment)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	RuleHitOutsideRange         DiagnosticKind = "hit-outside-range"
+	RuleRangeExitWithoutEntry   DiagnosticKind = "range-exit-without-entry"
+	RulePenaltyExitWithoutEntry DiagnosticKind = "penalty-exit-without-entry"
+	RuleTimeOutOfOrder          DiagnosticKind = "time-out-of-order"
+	RuleStartOutsideSchedule    DiagnosticKind = "start-outside-schedule"
+	RuleLapBeyondConfig         DiagnosticKind = "lap-beyond-config"
+)
+
+type lintState struct {
+	onRange   bool
+	inPenalty bool
+	laps      int
+}
+
+// LintEvents checks a parsed events log for logical mistakes, walking the
+// events in file order. Rules that depend on the race setup are skipped when
+// config is nil.
+func LintEvents(events []Event, config *Config) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(event Event, rule DiagnosticKind, severity Severity, format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     event.Source.File,
+			Line:     event.Source.Line,
+			Column:   1,
+			Offset:   event.Source.Offset,
+			Raw:      event.Line,
+			Kind:     rule,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	states := make(map[int]*lintState)
+	var previous time.Time
+	for i, event := range events {
+		if i > 0 && event.Timestamp.Before(previous) {
+			report(event, RuleTimeOutOfOrder, SeverityError, "event at %s is earlier than the previous event at %s", FormatTime(event.Timestamp), FormatTime(previous))
+		} else {
+			previous = event.Timestamp
+		}
+
+		state, ok := states[event.CompetitorID]
+		if !ok {
+			state = &lintState{}
+			states[event.CompetitorID] = state
+		}
+
+		switch event.ID {
+		case EventStartTimeSet:
+			if config != nil {
+				lintScheduledStart(event, config, report)
+			}
+		case EventOnFiringRange:
+			state.onRange = true
+		case EventTargetHit, EventShotFired, EventSpareLoaded:
+			if !state.onRange {
+				report(event, RuleHitOutsideRange, SeverityError, "competitor %d fired without entering a firing range", event.CompetitorID)
+			}
+		case EventLeftFiringRange:
+			if !state.onRange {
+				report(event, RuleRangeExitWithoutEntry, SeverityError, "competitor %d left a firing range they did not enter", event.CompetitorID)
+			}
+			state.onRange = false
+		case EventEnteredPenaltyLaps:
+			state.inPenalty = true
+		case EventLeftPenaltyLaps:
+			if !state.inPenalty {
+				report(event, RulePenaltyExitWithoutEntry, SeverityError, "competitor %d left the penalty laps without entering them", event.CompetitorID)
+			}
+			state.inPenalty = false
+		case EventEndedMainLap:
+			state.laps++
+			if config != nil && state.laps > config.Laps {
+				report(event, RuleLapBeyondConfig, SeverityError, "competitor %d ended lap %d but the race has %d laps", event.CompetitorID, state.laps, config.Laps)
+			}
+		}
+	}
+	return diagnostics
+}
+
+// lintScheduledStart checks a drawn start time against the schedule: no start
+// before the race start, and every start on the start interval grid.
+func lintScheduledStart(event Event, config *Config, report func(Event, DiagnosticKind, Severity, string, ...any)) {
+	offset := event.ScheduledStartTime.Sub(config.StartTime)
+	if offset < 0 {
+		report(event, RuleStartOutsideSchedule, SeverityError, "start time %s is before the race start %s", FormatTime(event.ScheduledStartTime), FormatTime(config.StartTime))
+		return
+	}
+	if config.StartDelta > 0 && offset%config.StartDelta != 0 {
+		report(event, RuleStartOutsideSchedule, SeverityWarning, "start time %s is not on the %s start interval from %s", FormatTime(event.ScheduledStartTime), FormatDuration(config.StartDelta), FormatTime(config.StartTime))
+	}
+}
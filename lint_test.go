@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintEvents(t *testing.T) {
+	ev := func(line int, h, m, s int, id EventID, competitor int) Event {
+		return Event{Timestamp: testTime(h, m, s, 0), ID: id, CompetitorID: competitor, Source: SourcePos{Line: line}}
+	}
+	scheduled := func(line, competitor int, start string) Event {
+		e := ev(line, 9, 0, 0, EventStartTimeSet, competitor)
+		e.ScheduledStartTime, _ = ParseTime(start)
+		return e
+	}
+
+	tests := []struct {
+		name      string
+		events    []Event
+		noConfig  bool
+		wantRules []DiagnosticKind
+		wantLines []int
+		wantError []bool
+	}{
+		{
+			name: "Clean",
+			events: []Event{
+				scheduled(1, 1, "10:01:00.000"),
+				ev(2, 10, 1, 0, EventStarted, 1),
+				ev(3, 10, 5, 0, EventOnFiringRange, 1),
+				ev(4, 10, 5, 10, EventTargetHit, 1),
+				ev(5, 10, 5, 30, EventLeftFiringRange, 1),
+				ev(6, 10, 5, 40, EventEnteredPenaltyLaps, 1),
+				ev(7, 10, 6, 40, EventLeftPenaltyLaps, 1),
+				ev(8, 10, 20, 0, EventEndedMainLap, 1),
+			},
+		},
+		{
+			name: "RangeAndPenalty",
+			events: []Event{
+				ev(1, 10, 5, 0, EventTargetHit, 1),
+				ev(2, 10, 5, 30, EventLeftFiringRange, 1),
+				ev(3, 10, 6, 0, EventLeftPenaltyLaps, 1),
+				ev(4, 10, 7, 0, EventOnFiringRange, 2),
+				ev(5, 10, 7, 5, EventShotFired, 2),
+			},
+			wantRules: []DiagnosticKind{RuleHitOutsideRange, RuleRangeExitWithoutEntry, RulePenaltyExitWithoutEntry},
+			wantLines: []int{1, 2, 3},
+			wantError: []bool{true, true, true},
+		},
+		{
+			name: "TimeOutOfOrder",
+			events: []Event{
+				ev(1, 10, 0, 0, EventRegistered, 1),
+				ev(2, 9, 59, 0, EventRegistered, 2),
+				ev(3, 10, 0, 0, EventRegistered, 3),
+			},
+			wantRules: []DiagnosticKind{RuleTimeOutOfOrder},
+			wantLines: []int{2},
+			wantError: []bool{true},
+		},
+		{
+			name: "StartOutsideSchedule",
+			events: []Event{
+				scheduled(1, 1, "09:59:00.000"),
+				scheduled(2, 2, "10:01:30.000"),
+				scheduled(3, 3, "10:02:00.000"),
+			},
+			wantRules: []DiagnosticKind{RuleStartOutsideSchedule, RuleStartOutsideSchedule},
+			wantLines: []int{1, 2},
+			wantError: []bool{true, false},
+		},
+		{
+			name: "LapBeyondConfig",
+			events: []Event{
+				ev(1, 10, 20, 0, EventEndedMainLap, 1),
+				ev(2, 10, 40, 0, EventEndedMainLap, 1),
+			},
+			wantRules: []DiagnosticKind{RuleLapBeyondConfig},
+			wantLines: []int{2},
+			wantError: []bool{true},
+		},
+		{
+			name:     "ConfigRulesSkippedWithoutConfig",
+			noConfig: true,
+			events: []Event{
+				scheduled(1, 1, "09:59:00.000"),
+				ev(2, 10, 20, 0, EventEndedMainLap, 1),
+				ev(3, 10, 40, 0, EventEndedMainLap, 1),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			if tt.noConfig {
+				cfg = nil
+			}
+			got := LintEvents(tt.events, cfg)
+			if len(got) != len(tt.wantRules) {
+				t.Fatalf("LintEvents() got %d diagnostics, want %d: %v", len(got), len(tt.wantRules), got)
+			}
+			for i, d := range got {
+				if d.Kind != tt.wantRules[i] || d.Line != tt.wantLines[i] || d.IsError() != tt.wantError[i] {
+					t.Errorf("Diagnostic %d: got %s on line %d (error %v), want %s on line %d (error %v)",
+						i, d.Kind, d.Line, d.IsError(), tt.wantRules[i], tt.wantLines[i], tt.wantError[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRunLint_DefaultConfigMissing(t *testing.T) {
+	events := filepath.Join(t.TempDir(), "events")
+	if err := os.WriteFile(events, []byte("[09:05:59.867] 1 1\n[09:15:00.841] 2 1 09:30:00.000\n"), 0644); err != nil {
+		t.Fatalf("Failed to write events: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(executableDir(), "config.json")); err == nil {
+		t.Skip("A config.json next to the test binary would be loaded")
+	}
+
+	if got := runLint([]string{events}); got != 0 {
+		t.Errorf("runLint() without the default config = %d, want 0", got)
+	}
+	if got := runLint([]string{"-config=" + filepath.Join(t.TempDir(), "config.json"), events}); got != 2 {
+		t.Errorf("runLint() with a missing -config = %d, want 2", got)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+type StandingRow struct {
+	Position      int              `json:"position"`
+	CompetitorID  int              `json:"competitorId"`
+	Status        CompetitorStatus `json:"status"`
+	LapsCompleted int              `json:"lapsCompleted"`
+	Elapsed       string           `json:"elapsed,omitempty"`
+
+	elapsed time.Duration
+}
+
+type liveRecord struct {
+	Type         string        `json:"type"`
+	Time         string        `json:"time"`
+	Severity     Severity      `json:"severity,omitempty"`
+	EventID      EventID       `json:"eventId,omitempty"`
+	CompetitorID int           `json:"competitorId,omitempty"`
+	Params       string        `json:"params,omitempty"`
+	Generated    bool          `json:"generated,omitempty"`
+	Text         string        `json:"text,omitempty"`
+	Source       *SourcePos    `json:"source,omitempty"`
+	Standings    []StandingRow `json:"standings,omitempty"`
+}
+
+func lapsCompleted(c *Competitor) int {
+	completed := 0
+	for _, lap := range c.LapsData {
+		if !lap.EndTime.IsZero() {
+			completed++
+		}
+	}
+	return completed
+}
+
+// LiveStandings orders the field as it stands on the course: finishers by race
+// time, then racing competitors by laps completed and the elapsed time at their
+// last lap line, then everyone else in the final report's status order.
+func LiveStandings(competitors map[int]*Competitor) []StandingRow {
+	rows := make([]StandingRow, 0, len(competitors))
+	for _, c := range competitors {
+		row := StandingRow{
+			CompetitorID:  c.ID,
+			Status:        c.Status,
+			LapsCompleted: lapsCompleted(c),
+		}
+		if row.LapsCompleted > 0 && !c.ActualStartTime.IsZero() {
+			row.elapsed = c.LapsData[row.LapsCompleted-1].EndTime.Sub(c.ActualStartTime)
+			row.Elapsed = FormatDuration(row.elapsed)
+		}
+		rows = append(rows, row)
+	}
+
+	onCourse := func(r StandingRow) bool {
+		return r.Status != StatusNotFinished && r.Status != StatusNotStarted && r.Status != StatusDisqualified &&
+			r.Status != StatusRegistered && r.Status != StatusScheduled
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		r1, r2 := rows[i], rows[j]
+		if onCourse(r1) != onCourse(r2) {
+			return onCourse(r1)
+		}
+		if r1.LapsCompleted != r2.LapsCompleted {
+			return r1.LapsCompleted > r2.LapsCompleted
+		}
+		if r1.elapsed != r2.elapsed {
+			return r1.elapsed < r2.elapsed
+		}
+		if statusOrder(r1.Status) != statusOrder(r2.Status) {
+			return statusOrder(r1.Status) < statusOrder(r2.Status)
+		}
+		return r1.CompetitorID < r2.CompetitorID
+	})
+	for i := range rows {
+		rows[i].Position = i + 1
+	}
+	return rows
+}
+
+// LiveOutput streams what happens in a simulation while it is being fed: new
+// log lines to Log, the same lines as JSON records to JSON, and standings
+// snapshots every SnapshotEvery of race clock.
+type LiveOutput struct {
+	Log           io.Writer
+	JSON          io.Writer
+	SnapshotEvery time.Duration
+
+	logged       int
+	lastSnapshot time.Time
+}
+
+func (o *LiveOutput) writeJSON(record liveRecord) error {
+	if o.JSON == nil {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal live record: %w", err)
+	}
+	_, err = fmt.Fprintf(o.JSON, "%s\n", data)
+	return err
+}
+
+// Emit writes every log line the simulation produced since the last call and
+// takes a standings snapshot when the snapshot interval has elapsed.
+func (o *LiveOutput) Emit(sim *Simulation, event Event) error {
+	for _, entry := range sim.OutputLog[o.logged:] {
+		if o.Log != nil {
+			if _, err := fmt.Fprintln(o.Log, entry); err != nil {
+				return err
+			}
+		}
+		record := liveRecord{
+			Type:         "log",
+			Time:         FormatTime(entry.Time),
+			Severity:     entry.Severity,
+			EventID:      entry.EventID,
+			CompetitorID: entry.CompetitorID,
+			Params:       entry.Params,
+			Generated:    entry.Generated,
+			Text:         entry.Message,
+		}
+		if entry.Source.Line > 0 {
+			record.Source = &entry.Source
+		}
+		if err := o.writeJSON(record); err != nil {
+			return err
+		}
+	}
+	o.logged = len(sim.OutputLog)
+
+	if o.SnapshotEvery > 0 {
+		if o.lastSnapshot.IsZero() {
+			o.lastSnapshot = event.Timestamp
+		} else if event.Timestamp.Sub(o.lastSnapshot) >= o.SnapshotEvery {
+			return o.Snapshot(sim, event.Timestamp)
+		}
+	}
+	return nil
+}
+
+// Skip marks everything already in the simulation log as emitted, used when
+// the feed jumps ahead without replaying the intermediate output.
+func (o *LiveOutput) Skip(sim *Simulation) {
+	o.logged = len(sim.OutputLog)
+}
+
+func (o *LiveOutput) Snapshot(sim *Simulation, clock time.Time) error {
+	o.lastSnapshot = clock
+	rows := LiveStandings(sim.Competitors)
+
+	if o.Log != nil {
+		fmt.Fprintf(o.Log, "Standings at %s\n", FormatTime(clock))
+		for _, row := range rows {
+			elapsed := row.Elapsed
+			if elapsed == "" {
+				elapsed = "-"
+			}
+			fmt.Fprintf(o.Log, "%3d. %-5d %-12s laps %d %s\n", row.Position, row.CompetitorID, row.Status, row.LapsCompleted, elapsed)
+		}
+	}
+	return o.writeJSON(liveRecord{Type: "standings", Time: FormatTime(clock), Standings: rows})
+}
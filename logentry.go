@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// LogEntry is one line of the simulation log: an incoming event, an event
+// generated by the simulation or a warning raised while processing an event.
+type LogEntry struct {
+	Time         time.Time
+	EventID      EventID
+	CompetitorID int
+	Params       string
+	Generated    bool
+	Severity     Severity
+	Message      string
+	Source       SourcePos
+}
+
+type logRecord struct {
+	Time         string     `json:"time"`
+	Severity     Severity   `json:"severity"`
+	EventID      EventID    `json:"eventId"`
+	CompetitorID int        `json:"competitorId"`
+	Params       string     `json:"params,omitempty"`
+	Generated    bool       `json:"generated,omitempty"`
+	Message      string     `json:"message"`
+	Source       *SourcePos `json:"source,omitempty"`
+}
+
+func newEventEntry(event Event, generated bool) LogEntry {
+	return LogEntry{
+		Time:         event.Timestamp,
+		EventID:      event.ID,
+		CompetitorID: event.CompetitorID,
+		Params:       FormatEventParams(event),
+		Generated:    generated,
+		Severity:     SeverityInfo,
+		Message:      GetEventDescription(event),
+		Source:       event.Source,
+	}
+}
+
+// String renders the entry as a line of the text log. Warnings name the
+// events file line that raised them.
+func (e LogEntry) String() string {
+	if e.Severity == SeverityWarning {
+		if e.Source.Line > 0 {
+			return fmt.Sprintf("Warning: %s (%s)", e.Message, e.Source)
+		}
+		return "Warning: " + e.Message
+	}
+	return fmt.Sprintf("[%s] %s", FormatTime(e.Time), e.Message)
+}
+
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	record := logRecord{
+		Time:         FormatTime(e.Time),
+		Severity:     e.Severity,
+		EventID:      e.EventID,
+		CompetitorID: e.CompetitorID,
+		Params:       e.Params,
+		Generated:    e.Generated,
+		Message:      e.Message,
+	}
+	if e.Source.Line > 0 {
+		record.Source = &e.Source
+	}
+	return json.Marshal(record)
+}
+
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+	var record logRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	t, err := ParseTime(record.Time)
+	if err != nil {
+		return err
+	}
+	*e = LogEntry{
+		Time:         t,
+		EventID:      record.EventID,
+		CompetitorID: record.CompetitorID,
+		Params:       record.Params,
+		Generated:    record.Generated,
+		Severity:     record.Severity,
+		Message:      record.Message,
+	}
+	if record.Source != nil {
+		e.Source = *record.Source
+	}
+	return nil
+}
+
+func (s *Simulation) logEvent(event Event, generated bool) {
+	s.OutputLog = append(s.OutputLog, newEventEntry(event, generated))
+}
+
+// warn records a warning about competitorID raised while processing event.
+func (s *Simulation) warn(event Event, competitorID int, format string, args ...any) {
+	s.OutputLog = append(s.OutputLog, LogEntry{
+		Time:         event.Timestamp,
+		EventID:      event.ID,
+		CompetitorID: competitorID,
+		Severity:     SeverityWarning,
+		Message:      fmt.Sprintf(format, args...),
+		Source:       event.Source,
+	})
+}
+
+func WriteLogText(w io.Writer, entries []LogEntry) error {
+	for _, entry := range entries {
+		if _, err := fmt.Fprintln(w, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteLogJSONL writes the log as JSON Lines, one object per entry.
+func WriteLogJSONL(w io.Writer, entries []LogEntry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write log entry: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSimulation_StructuredLog(t *testing.T) {
+	cfg := createTestConfig()
+	sim := NewSimulation(cfg)
+	sim.Run([]Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 1, 0, 0), ID: EventTargetHit, CompetitorID: 1, Target: 2},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+	})
+
+	want := []struct {
+		severity  Severity
+		eventID   EventID
+		generated bool
+		text      string
+	}{
+		{SeverityInfo, EventStarted, false, "[10:00:00.000] The competitor(1) has started"},
+		{SeverityInfo, EventTargetHit, false, "[10:01:00.000] The target(2) has been hit by competitor(1)"},
+		{SeverityWarning, EventTargetHit, false, "Warning: Competitor 1 (Racing) received TargetHit event but is not on firing range."},
+		{SeverityInfo, EventEndedMainLap, false, "[10:05:00.000] The competitor(1) ended the main lap"},
+		{SeverityInfo, EventFinished, true, "[10:05:00.000] The competitor(1) has finished"},
+	}
+	if len(sim.OutputLog) != len(want) {
+		t.Fatalf("Expected %d log entries, got %d: %v", len(want), len(sim.OutputLog), sim.OutputLog)
+	}
+	for i, w := range want {
+		entry := sim.OutputLog[i]
+		if entry.Severity != w.severity || entry.EventID != w.eventID || entry.Generated != w.generated || entry.CompetitorID != 1 {
+			t.Errorf("Entry %d: got %+v", i, entry)
+		}
+		if entry.String() != w.text {
+			t.Errorf("Entry %d text: got %q, want %q", i, entry.String(), w.text)
+		}
+	}
+	if sim.OutputLog[1].Params != "2" {
+		t.Errorf("Expected params '2', got %q", sim.OutputLog[1].Params)
+	}
+}
+
+func TestWriteLogJSONL(t *testing.T) {
+	entries := []LogEntry{
+		newEventEntry(Event{Timestamp: testTime(9, 30, 0, 0), ID: EventOnFiringRange, CompetitorID: 3, FiringRange: 1}, false),
+		{Time: testTime(9, 31, 0, 0), EventID: EventLeftFiringRange, CompetitorID: 3, Severity: SeverityWarning, Message: "Competitor 3 left twice."},
+	}
+	var buf bytes.Buffer
+	if err := WriteLogJSONL(&buf, entries); err != nil {
+		t.Fatalf("WriteLogJSONL() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(lines))
+	}
+	var records []logRecord
+	for _, line := range lines {
+		var record logRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	if records[0].Time != "09:30:00.000" || records[0].EventID != EventOnFiringRange || records[0].Params != "1" || records[0].Severity != SeverityInfo {
+		t.Errorf("Unexpected event record: %+v", records[0])
+	}
+	if records[1].Severity != SeverityWarning || records[1].Message != "Competitor 3 left twice." {
+		t.Errorf("Unexpected warning record: %+v", records[1])
+	}
+}
+
+func TestLogEntry_WarningSource(t *testing.T) {
+	sim := NewSimulation(createTestConfig())
+	event := Event{Timestamp: testTime(10, 0, 0, 0), ID: EventLeftPenaltyLaps, CompetitorID: 4, Source: SourcePos{File: "events", Line: 12, Offset: 300}}
+	sim.warn(event, 4, "Competitor %d left the penalty laps twice.", 4)
+
+	entry := sim.OutputLog[0]
+	if got, want := entry.String(), "Warning: Competitor 4 left the penalty laps twice. (events:12)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"source":{"file":"events","line":12,"offset":300}`) {
+		t.Errorf("Expected source in JSON, got %s", data)
+	}
+}
@@ -1,53 +1,817 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
-func main() {
-	configFile := flag.String("config", "config.json", "Path to the configuration file")
-	eventsFile := flag.String("events", "events", "Path to the events file")
-	flag.Parse()
+func resolvePath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
 
-	baseDir := "."
+func executableDir() string {
 	exePath, err := os.Executable()
-	if err == nil {
-		baseDir = filepath.Dir(exePath)
+	if err != nil {
+		return "."
+	}
+	return filepath.Dir(exePath)
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		case "generate":
+			runGenerate(os.Args[2:])
+			return
+		case "predict":
+			runPredict(os.Args[2:])
+			return
+		case "history":
+			runHistory(os.Args[2:])
+			return
+		case "season":
+			runSeason(os.Args[2:])
+			return
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "convert":
+			runConvert(os.Args[2:])
+			return
+		}
+	}
+	runSimulation(os.Args[1:])
+}
+
+// stringList is a flag that can be given several times or as a
+// comma-separated list.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, strings.Split(value, ",")...)
+	return nil
+}
+
+// eventsFlags are the flags that select the events files of a race.
+type eventsFlags struct {
+	paths      stringList
+	offsets    stringList
+	rule       string
+	window     time.Duration
+	sync       bool
+	syncWindow time.Duration
+}
+
+func addEventsFlags(flags *flag.FlagSet, usage string) *eventsFlags {
+	f := &eventsFlags{}
+	flags.Var(&f.paths, "events", usage+"; repeat or pass a directory to merge several timing stations (default \"events\")")
+	flags.Var(&f.offsets, "clock-offset", "Clock correction for a timing station as <file name>=<duration>, e.g. finish.log=-250ms; can be repeated")
+	flags.StringVar(&f.rule, "duplicates", string(DuplicateEarliest), "How to resolve an event reported by several stations: earliest, latest, priority or keep")
+	flags.DurationVar(&f.window, "duplicate-window", DefaultDuplicateWindow, "Largest time difference between two stations' reports of the same event")
+	flags.BoolVar(&f.sync, "sync", false, "Estimate each station's clock offset and drift from shared events and correct its times")
+	flags.DurationVar(&f.syncWindow, "sync-window", DefaultSyncWindow, "Largest clock difference at which two stations' events are treated as the same for clock sync")
+	return f
+}
+
+func (f *eventsFlags) resolvedPaths(baseDir string) []string {
+	paths := f.paths
+	if len(paths) == 0 {
+		paths = stringList{"events"}
+	}
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		resolved[i] = resolvePath(baseDir, path)
+	}
+	return resolved
+}
+
+// sources resolves the events paths and applies the clock offsets.
+func (f *eventsFlags) sources(baseDir string) ([]EventSource, MergeOptions, error) {
+	rule, err := ParseDuplicateRule(f.rule)
+	if err != nil {
+		return nil, MergeOptions{}, err
 	}
+	opts := MergeOptions{Rule: rule, Window: f.window, Sync: f.sync, SyncWindow: f.syncWindow}
 
-	absConfigFile := *configFile
-	if !filepath.IsAbs(absConfigFile) {
-		absConfigFile = filepath.Join(baseDir, *configFile)
+	sources, err := ExpandEventSources(f.resolvedPaths(baseDir))
+	if err != nil {
+		return nil, opts, err
 	}
 
-	absEventsFile := *eventsFile
-	if !filepath.IsAbs(absEventsFile) {
-		absEventsFile = filepath.Join(baseDir, *eventsFile)
+	for _, offset := range f.offsets {
+		name, durationStr, ok := strings.Cut(offset, "=")
+		if !ok {
+			return nil, opts, fmt.Errorf("invalid clock offset '%s', expected <file name>=<duration>", offset)
+		}
+		d, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return nil, opts, fmt.Errorf("invalid clock offset '%s': %w", offset, err)
+		}
+		found := false
+		for i := range sources {
+			if sources[i].Name == name {
+				sources[i].ClockOffset = d
+				found = true
+			}
+		}
+		if !found {
+			return nil, opts, fmt.Errorf("clock offset for unknown events source '%s'", name)
+		}
 	}
+	return sources, opts, nil
+}
+
+// loadRace loads the configuration, events and optional roster of a race.
+// Events file diagnostics and the clock sync report are written to diag; in
+// strict mode the first error is returned instead.
+func loadRace(diag io.Writer, baseDir, configFile string, events *eventsFlags, athletesFile string, strict bool) (*Config, []Event, Roster, error) {
+	absConfigFile := resolvePath(baseDir, configFile)
 
 	cfg, err := LoadConfig(absConfigFile)
 	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
+		return nil, nil, nil, fmt.Errorf("error loading configuration: %w", err)
 	}
-	fmt.Printf("Configuration loaded from %s: %+v\n\n", absConfigFile, cfg)
 
-	incomingEvents, err := LoadEvents(absEventsFile)
+	sources, opts, err := events.sources(baseDir)
 	if err != nil {
-		log.Fatalf("Error loading events: %v", err)
+		return nil, nil, nil, fmt.Errorf("error loading events: %w", err)
 	}
-	fmt.Printf("Loaded %d events from %s.\n\n", len(incomingEvents), absEventsFile)
+	incomingEvents, diagnostics, estimates, err := LoadMergedEvents(sources, opts)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error loading events: %w", err)
+	}
+	if strict {
+		for _, d := range diagnostics {
+			if d.IsError() {
+				return nil, nil, nil, fmt.Errorf("error loading events: %w", d)
+			}
+		}
+	}
+	WriteDiagnostics(diag, diagnostics)
+	WriteSyncReport(diag, estimates)
+
+	var roster Roster
+	if athletesFile != "" {
+		roster, err = LoadRoster(resolvePath(baseDir, athletesFile))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("error loading athletes roster: %w", err)
+		}
+	}
+	return cfg, incomingEvents, roster, nil
+}
 
+func newRaceSimulation(cfg *Config, roster Roster) *Simulation {
 	simulation := NewSimulation(cfg)
+	if roster != nil {
+		simulation.ApplyRoster(roster)
+	}
+	return simulation
+}
+
+func printFinalReports(w io.Writer, simulation *Simulation, cfg *Config) {
+	GenerateFinalReport(w, simulation.Competitors, cfg)
+	if cfg.IsRelay() {
+		fmt.Fprintln(w)
+		GenerateTeamReport(w, simulation.Competitors, cfg)
+	}
+}
 
-	simulation.Run(incomingEvents)
+// runRace processes the events log from the simulation's position to the end,
+// applies the official corrections and finalizes the results.
+func runRace(simulation *Simulation, events []Event, corrections []Correction) ([]CorrectionAudit, error) {
+	events, audit := ApplyEventCorrections(events, corrections)
+	if err := simulation.Resume(events); err != nil {
+		return nil, err
+	}
+	audit = append(audit, simulation.ApplyCorrections(corrections)...)
 	simulation.FinalizeResults()
+	return audit, nil
+}
+
+func loadSnapshot(path string) (*SimulationSnapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open simulation snapshot '%s': %w", path, err)
+	}
+	defer file.Close()
+	return ReadSnapshot(file)
+}
+
+func saveSnapshot(path string, simulation *Simulation) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create simulation snapshot '%s': %w", path, err)
+	}
+	defer file.Close()
+	return WriteSnapshot(file, simulation.Snapshot())
+}
+
+// printRaceResults writes the corrections audit, when there were corrections,
+// and the final reports.
+func printRaceResults(w io.Writer, simulation *Simulation, cfg *Config, corrections []Correction, audit []CorrectionAudit) {
+	if len(corrections) > 0 {
+		GenerateCorrectionsLog(w, audit)
+	}
+	printFinalReports(w, simulation, cfg)
+}
+
+func runSimulation(args []string) {
+	err := run(args, os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// run is the default command: it simulates a race and writes the output log
+// and reports to stdout, and the events file diagnostics to stderr.
+func run(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("BiathlonSim", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configFile := flags.String("config", "config.json", "Path to the configuration file")
+	eventsFiles := addEventsFlags(flags, "Path to the events file")
+	athletesFile := flags.String("athletes", "", "Path to the athletes roster file with competitor attributes")
+	strict := flags.Bool("strict", false, "Fail on the first malformed line of the events file")
+	groupBy := flags.String("group-by", "", "Comma-separated competitor attributes to rank by, e.g. gender,category")
+	groupExportDir := flags.String("group-export", "", "Directory to write one CSV file per result group")
+	correctionsFile := flags.String("corrections", "", "Path to the official timing corrections file")
+	logJSONFile := flags.String("log-json", "", "Path to write the output log to as JSON Lines, '-' for stdout")
+	resumeFile := flags.String("resume", "", "Path to a simulation snapshot to continue the race from")
+	saveStateFile := flags.String("save-state", "", "Path to write a simulation snapshot to once the events are processed")
+	stateAt := flags.String("state-at", "", "Race clock time (HH:MM:SS[.mmm]) to stop at and save the snapshot, requires -save-state")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	baseDir := executableDir()
+
+	cfg, incomingEvents, roster, err := loadRace(stderr, baseDir, *configFile, eventsFiles, *athletesFile, *strict)
+	if err != nil {
+		return err
+	}
+	var corrections []Correction
+	if *correctionsFile != "" {
+		corrections, err = LoadCorrections(resolvePath(baseDir, *correctionsFile))
+		if err != nil {
+			return fmt.Errorf("error loading corrections: %w", err)
+		}
+	}
+	if *groupBy != "" {
+		cfg.GroupBy, err = ParseGroupBy(*groupBy)
+		if err != nil {
+			return fmt.Errorf("error parsing -group-by: %w", err)
+		}
+	}
+	fmt.Fprintf(stdout, "Configuration loaded from %s: %+v\n\n", resolvePath(baseDir, *configFile), cfg)
+	fmt.Fprintf(stdout, "Loaded %d events from %s.\n\n", len(incomingEvents), strings.Join(eventsFiles.resolvedPaths(baseDir), ", "))
+
+	simulation := newRaceSimulation(cfg, roster)
+	if *resumeFile != "" {
+		snapshot, err := loadSnapshot(resolvePath(baseDir, *resumeFile))
+		if err != nil {
+			return fmt.Errorf("error loading simulation snapshot: %w", err)
+		}
+		simulation = RestoreSimulation(cfg, snapshot)
+		if roster != nil {
+			simulation.ApplyRoster(roster)
+		}
+		fmt.Fprintf(stdout, "Resuming after event %d %s.\n\n", snapshot.Position.Index, snapshot.Position)
+	}
+
+	if *stateAt != "" && *saveStateFile == "" {
+		return fmt.Errorf("-state-at requires -save-state")
+	}
+	if *saveStateFile != "" {
+		var until time.Time
+		if *stateAt != "" {
+			until, err = ParseTime(*stateAt)
+			if err != nil {
+				return fmt.Errorf("error parsing snapshot time: %w", err)
+			}
+		}
+		events, _ := ApplyEventCorrections(incomingEvents, corrections)
+		if err := simulation.Advance(events, until); err != nil {
+			return fmt.Errorf("error processing events: %w", err)
+		}
+		// The snapshot is taken before the race is closed so it can be
+		// resumed with events that arrive later.
+		if err := saveSnapshot(resolvePath(baseDir, *saveStateFile), simulation); err != nil {
+			return fmt.Errorf("error saving simulation snapshot: %w", err)
+		}
+		fmt.Fprintf(stdout, "Saved the simulation state after event %d %s to %s.\n\n", simulation.Position.Index, simulation.Position, resolvePath(baseDir, *saveStateFile))
+		if !until.IsZero() {
+			return nil
+		}
+	}
+
+	audit, err := runRace(simulation, incomingEvents, corrections)
+	if err != nil {
+		return fmt.Errorf("error processing events: %w", err)
+	}
+
+	GenerateOutputLog(stdout, simulation.OutputLog)
+	if *logJSONFile != "" {
+		if err := exportLogJSON(stdout, resolvePath(baseDir, *logJSONFile), *logJSONFile == "-", simulation.OutputLog); err != nil {
+			return fmt.Errorf("error writing JSON log: %w", err)
+		}
+	}
+	printRaceResults(stdout, simulation, cfg, corrections, audit)
+
+	if *groupExportDir != "" && len(cfg.GroupBy) > 0 {
+		if err := exportGroups(resolvePath(baseDir, *groupExportDir), simulation, cfg); err != nil {
+			return fmt.Errorf("error exporting result groups: %w", err)
+		}
+	}
+
+	fmt.Fprintln(stdout, "\nBiathlonSim finished.")
+	return nil
+}
+
+// runLint checks events files and prints every problem found. It returns the
+// process exit code: 1 when any file has errors, 2 when a file cannot be read.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	configFile := flags.String("config", "config.json", "Path to the configuration file, empty to skip the schedule and lap checks")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: BiathlonSim lint [flags] [events files...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files = []string{"events"}
+	}
+
+	baseDir := executableDir()
+	configSet := false
+	flags.Visit(func(f *flag.Flag) {
+		configSet = configSet || f.Name == "config"
+	})
+	var cfg *Config
+	if *configFile != "" {
+		path := resolvePath(baseDir, *configFile)
+		if _, err := os.Stat(path); !configSet && errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "No configuration at %s, skipping the schedule and lap checks\n", path)
+		} else {
+			cfg, err = LoadConfig(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				return 2
+			}
+		}
+	}
+
+	exitCode := 0
+	errorCount, warningCount := 0, 0
+	for _, file := range files {
+		path := resolvePath(baseDir, file)
+		events, diagnostics, err := LoadEvents(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+			continue
+		}
+		diagnostics = append(diagnostics, LintEvents(events, cfg)...)
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].Line < diagnostics[j].Line
+		})
+		WriteDiagnostics(os.Stdout, diagnostics)
+		for _, d := range diagnostics {
+			if d.IsError() {
+				errorCount++
+			} else {
+				warningCount++
+			}
+		}
+	}
+
+	if errorCount+warningCount > 0 {
+		fmt.Fprintf(os.Stderr, "%d error(s), %d warning(s)\n", errorCount, warningCount)
+	}
+	if errorCount > 0 && exitCode == 0 {
+		exitCode = 1
+	}
+	return exitCode
+}
+
+func runConvert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	inFile := flags.String("in", "events", "Path to the events file to convert, text or binary")
+	outFile := flags.String("out", "-", "Path to write the converted events file to, '-' for stdout")
+	to := flags.String("to", "", "Output format, text or binary; defaults to the other format than the input")
+	var meta stringList
+	flags.Var(&meta, "meta", "Race metadata for the binary header as key=value, e.g. race=oberhof-sprint; can be repeated")
+	flags.Parse(args)
+
+	baseDir := executableDir()
+	inPath := resolvePath(baseDir, *inFile)
 
-	GenerateOutputLog(simulation.OutputLog)
-	GenerateFinalReport(simulation.Competitors, cfg)
+	file, err := os.Open(inPath)
+	if err != nil {
+		log.Fatalf("Error opening events file: %v", err)
+	}
+	defer file.Close()
+	reader, binary := newSniffingReader(file)
+
+	var events []Event
+	var diagnostics []Diagnostic
+	metadata := make(map[string]string)
+	if binary {
+		events, diagnostics, metadata, err = ReadBinaryEvents(reader, inPath)
+	} else {
+		events, diagnostics, err = LoadEvents(inPath)
+	}
+	if err != nil {
+		log.Fatalf("Error loading events: %v", err)
+	}
+	WriteDiagnostics(os.Stderr, diagnostics)
+	for _, kv := range meta {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			log.Fatalf("Invalid metadata '%s', expected key=value", kv)
+		}
+		metadata[key] = value
+	}
+
+	format := *to
+	if format == "" {
+		format = "binary"
+		if binary {
+			format = "text"
+		}
+	}
+
+	out := os.Stdout
+	if *outFile != "-" {
+		out, err = os.Create(resolvePath(baseDir, *outFile))
+		if err != nil {
+			log.Fatalf("Error creating events file: %v", err)
+		}
+		defer out.Close()
+	}
+
+	switch format {
+	case "binary":
+		err = WriteBinaryEvents(out, events, metadata)
+	case "text":
+		WriteMetadata(os.Stderr, metadata)
+		err = WriteEvents(out, events)
+	default:
+		log.Fatalf("Unknown output format '%s', expected text or binary", format)
+	}
+	if err != nil {
+		log.Fatalf("Error writing events: %v", err)
+	}
+}
+
+func runReplay(args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configFile := flags.String("config", "config.json", "Path to the configuration file")
+	eventsFiles := addEventsFlags(flags, "Path to the recorded events file")
+	athletesFile := flags.String("athletes", "", "Path to the athletes roster file with competitor attributes")
+	strict := flags.Bool("strict", false, "Fail on the first malformed line of the events file")
+	speed := flags.Float64("speed", 1, "Replay speed relative to the race clock, 0 replays without waiting")
+	seek := flags.String("seek", "", "Race clock time (HH:MM:SS[.mmm]) to start the replay from")
+	pauseAt := flags.String("pause-at", "", "Race clock time (HH:MM:SS[.mmm]) to pause the replay at")
+	jsonFile := flags.String("json", "", "Path to write the JSON stream to, '-' for stdout")
+	snapshotEvery := flags.String("snapshot-every", "", "Race clock interval (HH:MM:SS) between standings snapshots")
+	flags.Parse(args)
+
+	baseDir := executableDir()
+
+	cfg, incomingEvents, roster, err := loadRace(os.Stderr, baseDir, *configFile, eventsFiles, *athletesFile, *strict)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	output := &LiveOutput{Log: os.Stdout}
+	if *snapshotEvery != "" {
+		output.SnapshotEvery, err = ParseDuration(*snapshotEvery)
+		if err != nil {
+			log.Fatalf("Error parsing snapshot interval: %v", err)
+		}
+	}
+	switch *jsonFile {
+	case "":
+	case "-":
+		output.JSON = os.Stdout
+	default:
+		file, err := os.Create(resolvePath(baseDir, *jsonFile))
+		if err != nil {
+			log.Fatalf("Error creating JSON stream file: %v", err)
+		}
+		defer file.Close()
+		output.JSON = file
+	}
+
+	replayer := NewReplayer(incomingEvents, func() *Simulation {
+		return newRaceSimulation(cfg, roster)
+	}, output, *speed)
+	replayer.Controls = ReadReplayCommands(os.Stdin, os.Stderr)
+
+	if *pauseAt != "" {
+		replayer.PauseAt, err = ParseTime(*pauseAt)
+		if err != nil {
+			log.Fatalf("Error parsing pause time: %v", err)
+		}
+	}
+	if *seek != "" {
+		clock, err := ParseTime(*seek)
+		if err != nil {
+			log.Fatalf("Error parsing seek time: %v", err)
+		}
+		replayer.reset()
+		if err := replayer.Seek(clock); err != nil {
+			log.Fatalf("Error seeking replay: %v", err)
+		}
+	}
+
+	if err := replayer.Run(); err != nil {
+		log.Fatalf("Error replaying events: %v", err)
+	}
+	replayer.Simulation.FinalizeResults()
+
+	fmt.Println()
+	printFinalReports(os.Stdout, replayer.Simulation, cfg)
+}
+
+func exportLogJSON(stdout io.Writer, path string, toStdout bool, entries []LogEntry) error {
+	if toStdout {
+		return WriteLogJSONL(stdout, entries)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create JSON log file '%s': %w", path, err)
+	}
+	defer file.Close()
+	return WriteLogJSONL(file, entries)
+}
+
+func exportGroups(dir string, simulation *Simulation, cfg *Config) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create export directory '%s': %w", dir, err)
+	}
+	for _, group := range GroupResults(RankCompetitors(simulation.Competitors, cfg), cfg.GroupBy, cfg) {
+		path := filepath.Join(dir, group.FileName())
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create group file '%s': %w", path, err)
+		}
+		err = WriteGroupCSV(file, group, cfg)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runGenerate(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	configFile := flags.String("config", "config.json", "Path to the configuration file")
+	competitors := flags.Int("competitors", 30, "Number of competitors to generate")
+	profileFile := flags.String("profile", "", "Path to a JSON race profile, defaults are used when empty")
+	seed := flags.Uint64("seed", 0, "Random seed, a time-based seed is chosen and reported when 0")
+	outFile := flags.String("out", "-", "Path to write the events file to, '-' for stdout")
+	flags.Parse(args)
+
+	baseDir := executableDir()
+
+	cfg, err := LoadConfig(resolvePath(baseDir, *configFile))
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
 
-	fmt.Println("\nBiathlonSim finished.")
+	profile := DefaultRaceProfile()
+	if *profileFile != "" {
+		profile, err = LoadRaceProfile(resolvePath(baseDir, *profileFile))
+		if err != nil {
+			log.Fatalf("Error loading race profile: %v", err)
+		}
+	}
+
+	if *seed == 0 {
+		*seed = uint64(time.Now().UnixNano())
+		fmt.Fprintf(os.Stderr, "Using seed %d\n", *seed)
+	}
+
+	out := os.Stdout
+	if *outFile != "-" {
+		out, err = os.Create(resolvePath(baseDir, *outFile))
+		if err != nil {
+			log.Fatalf("Error creating events file: %v", err)
+		}
+		defer out.Close()
+	}
+
+	events := GenerateRace(cfg, *competitors, profile, *seed)
+	if err := WriteEvents(out, events); err != nil {
+		log.Fatalf("Error writing events: %v", err)
+	}
+}
+
+func runPredict(args []string) {
+	flags := flag.NewFlagSet("predict", flag.ExitOnError)
+	configFile := flags.String("config", "config.json", "Path to the configuration file")
+	eventsFiles := addEventsFlags(flags, "Path to the events file with the race so far")
+	athletesFile := flags.String("athletes", "", "Path to the athletes roster file with competitor attributes")
+	strict := flags.Bool("strict", false, "Fail on the first malformed line of the events file")
+	at := flags.String("at", "", "Race clock time (HH:MM:SS[.mmm]) to predict from, later events are ignored")
+	iterations := flags.Int("iterations", 10000, "Number of simulated race finishes")
+	topN := flags.Int("top", 6, "Report the probability of finishing in the top N")
+	seed := flags.Uint64("seed", 1, "Random seed for the simulated finishes")
+	flags.Parse(args)
+
+	baseDir := executableDir()
+
+	cfg, incomingEvents, roster, err := loadRace(os.Stderr, baseDir, *configFile, eventsFiles, *athletesFile, *strict)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	opts := PredictionOptions{Iterations: *iterations, TopN: *topN, Seed: *seed}
+	if *at != "" {
+		opts.Clock, err = ParseTime(*at)
+		if err != nil {
+			log.Fatalf("Error parsing prediction time: %v", err)
+		}
+	}
+
+	simulation := newRaceSimulation(cfg, roster)
+	sort.SliceStable(incomingEvents, func(i, j int) bool {
+		return incomingEvents[i].Timestamp.Before(incomingEvents[j].Timestamp)
+	})
+	for _, event := range incomingEvents {
+		if !opts.Clock.IsZero() && event.Timestamp.After(opts.Clock) {
+			break
+		}
+		simulation.Step(event)
+	}
+
+	WritePredictions(os.Stdout, PredictOutcome(simulation, opts), *topN)
+}
+
+func runHistory(args []string) {
+	if len(args) == 0 || (args[0] != "record" && args[0] != "stats") {
+		log.Fatalf("Usage: BiathlonSim history record|stats [flags]")
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("history "+command, flag.ExitOnError)
+	storeDir := flags.String("store", "history", "Directory of the race history store")
+	configFile := flags.String("config", "config.json", "Path to the configuration file")
+	eventsFiles := addEventsFlags(flags, "Path to the events file")
+	athletesFile := flags.String("athletes", "", "Path to the athletes roster file with competitor attributes")
+	strict := flags.Bool("strict", false, "Fail on the first malformed line of the events file")
+	raceID := flags.String("race-id", "", "Identifier to record the race under")
+	date := flags.String("date", "", "Race date (YYYY-MM-DD), today when empty")
+	discipline := flags.String("discipline", "", "Discipline to record the race under, overrides the configuration")
+	athlete := flags.String("athlete", "", "Only show stats for this athlete")
+	from := flags.String("from", "", "First race date (YYYY-MM-DD) to include in stats")
+	to := flags.String("to", "", "Last race date (YYYY-MM-DD) to include in stats")
+	flags.Parse(args[1:])
+
+	baseDir := executableDir()
+
+	store, err := OpenHistoryStore(resolvePath(baseDir, *storeDir))
+	if err != nil {
+		log.Fatalf("Error opening history store: %v", err)
+	}
+
+	switch command {
+	case "record":
+		cfg, incomingEvents, roster, err := loadRace(os.Stderr, baseDir, *configFile, eventsFiles, *athletesFile, *strict)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		raceDate := time.Now()
+		if *date != "" {
+			raceDate, err = time.Parse(historyDateLayout, *date)
+			if err != nil {
+				log.Fatalf("Error parsing race date: %v", err)
+			}
+		}
+
+		if *discipline != "" {
+			cfg.Discipline = *discipline
+		}
+
+		simulation := newRaceSimulation(cfg, roster)
+		simulation.Run(incomingEvents)
+		simulation.FinalizeResults()
+
+		if err := store.RecordRace(*raceID, raceDate, simulation); err != nil {
+			log.Fatalf("Error recording race: %v", err)
+		}
+		fmt.Printf("Recorded race %s (%s) with %d competitors.\n", *raceID, raceDate.Format(historyDateLayout), len(simulation.Competitors))
+
+	case "stats":
+		var fromDate, toDate time.Time
+		if *from != "" {
+			if fromDate, err = time.Parse(historyDateLayout, *from); err != nil {
+				log.Fatalf("Error parsing -from date: %v", err)
+			}
+		}
+		if *to != "" {
+			if toDate, err = time.Parse(historyDateLayout, *to); err != nil {
+				log.Fatalf("Error parsing -to date: %v", err)
+			}
+		}
+
+		var stats []AthleteStats
+		if *athlete != "" {
+			s, err := store.AthleteStats(*athlete, fromDate, toDate)
+			if err != nil {
+				log.Fatalf("Error querying history: %v", err)
+			}
+			stats = []AthleteStats{s}
+		} else {
+			stats, err = store.SeasonStats(fromDate, toDate)
+			if err != nil {
+				log.Fatalf("Error querying history: %v", err)
+			}
+		}
+		WriteAthleteStats(os.Stdout, stats)
+	}
+}
+
+func runSeason(args []string) {
+	flags := flag.NewFlagSet("season", flag.ExitOnError)
+	storeDir := flags.String("store", "", "Directory of the race history store to score")
+	pointsFile := flags.String("points", "", "Path to a JSON array of points per rank, IBU World Cup points when empty")
+	dropWorst := flags.Int("drop-worst", 0, "Number of worst results to drop for every athlete")
+	format := flags.String("format", "text", "Output format: text, json or csv")
+	flags.Parse(args)
+
+	baseDir := executableDir()
+
+	var races []RaceHistory
+	if *storeDir != "" {
+		store, err := OpenHistoryStore(resolvePath(baseDir, *storeDir))
+		if err != nil {
+			log.Fatalf("Error opening history store: %v", err)
+		}
+		races, err = store.Races(time.Time{}, time.Time{})
+		if err != nil {
+			log.Fatalf("Error reading history store: %v", err)
+		}
+	}
+	for _, path := range flags.Args() {
+		race, err := LoadRaceHistory(resolvePath(baseDir, path))
+		if err != nil {
+			log.Fatalf("Error loading race results: %v", err)
+		}
+		races = append(races, race)
+	}
+	if len(races) == 0 {
+		log.Fatalf("No race results given, use -store or list race result files")
+	}
+
+	table := PointsTable(IBUPointsTable)
+	if *pointsFile != "" {
+		var err error
+		table, err = LoadPointsTable(resolvePath(baseDir, *pointsFile))
+		if err != nil {
+			log.Fatalf("Error loading points table: %v", err)
+		}
+	}
+
+	all := []SeasonStandings{ComputeSeasonStandings(races, table, *dropWorst)}
+	all = append(all, ComputeDisciplineStandings(races, table, *dropWorst)...)
+
+	var err error
+	switch *format {
+	case "text":
+		err = WriteSeasonText(os.Stdout, all)
+	case "json":
+		err = WriteSeasonJSON(os.Stdout, all)
+	case "csv":
+		err = WriteSeasonCSV(os.Stdout, all)
+	default:
+		log.Fatalf("Unknown output format '%s'", *format)
+	}
+	if err != nil {
+		log.Fatalf("Error writing season standings: %v", err)
+	}
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata/golden")
+
+// TestGolden runs the default command on every race under testdata/golden and
+// compares each output with the expected file next to it. A race directory
+// holds config.json and an events file or directory, and optionally
+// athletes.json, corrections and args.
+func TestGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "golden", "*"))
+	if err != nil {
+		t.Fatalf("Failed to list golden races: %v", err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("No golden races found")
+	}
+
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			outputs := runGoldenRace(t, dir)
+			for name, got := range outputs {
+				path := filepath.Join(dir, "expected", name)
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+					}
+					if err := os.WriteFile(path, got, 0644); err != nil {
+						t.Fatalf("Failed to update %s: %v", path, err)
+					}
+					continue
+				}
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("Failed to read golden file, run 'go test -run TestGolden -update' to create it: %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s differs from the golden file:\n--- got\n%s\n--- want\n%s", path, got, want)
+				}
+			}
+
+			expectedDir := filepath.Join(dir, "expected")
+			err := filepath.WalkDir(expectedDir, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				rel, err := filepath.Rel(expectedDir, path)
+				if err != nil {
+					return err
+				}
+				if _, ok := outputs[filepath.ToSlash(rel)]; !ok {
+					t.Errorf("Golden file %s is no longer produced", path)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to list golden files: %v", err)
+			}
+		})
+	}
+}
+
+// runGoldenRace runs the default command on the race in dir and returns its
+// outputs keyed by golden file name: stdout.txt, stderr.txt when anything
+// was written to it, and every file the command wrote, such as log.jsonl and
+// the group exports. An optional args file in dir holds extra flags, one per
+// line, where $DIR is the race directory and $OUT the directory for written
+// files. The outputs name them as dir and $OUT so they do not depend on where
+// the tests run.
+func runGoldenRace(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", dir, err)
+	}
+	outDir := t.TempDir()
+	args := []string{
+		"-config=" + filepath.Join(absDir, "config.json"),
+		"-events=" + filepath.Join(absDir, "events"),
+		"-log-json=" + filepath.Join(outDir, "log.jsonl"),
+		"-group-export=" + filepath.Join(outDir, "groups"),
+	}
+	for _, optional := range []struct{ flag, file string }{{"athletes", "athletes.json"}, {"corrections", "corrections"}} {
+		if _, err := os.Stat(filepath.Join(dir, optional.file)); err == nil {
+			args = append(args, "-"+optional.flag+"="+filepath.Join(absDir, optional.file))
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "args")); err == nil {
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			args = append(args, os.Expand(strings.TrimSpace(line), func(name string) string {
+				return map[string]string{"DIR": absDir, "OUT": outDir}[name]
+			}))
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run(args, &stdout, &stderr); err != nil {
+		t.Fatalf("run(%q) error = %v\n%s", args, err, stderr.String())
+	}
+	scrub := func(data []byte) []byte {
+		data = bytes.ReplaceAll(data, []byte(outDir), []byte("$OUT"))
+		return bytes.ReplaceAll(data, []byte(absDir), []byte(filepath.ToSlash(dir)))
+	}
+
+	outputs := map[string][]byte{"stdout.txt": scrub(stdout.Bytes())}
+	if stderr.Len() > 0 {
+		outputs["stderr.txt"] = scrub(stderr.Bytes())
+	}
+	err = filepath.WalkDir(outDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		outputs[filepath.ToSlash(rel)] = scrub(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to read the written files: %v", err)
+	}
+	return outputs
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventSource is one timing station's events file. ClockOffset is added to
+// every timestamp read from it to bring the station's clock in line with the
+// official race clock.
+type EventSource struct {
+	Name        string
+	Path        string
+	ClockOffset time.Duration
+}
+
+type DuplicateRule string
+
+const (
+	DuplicateEarliest DuplicateRule = "earliest"
+	DuplicateLatest   DuplicateRule = "latest"
+	DuplicatePriority DuplicateRule = "priority"
+	DuplicateKeepAll  DuplicateRule = "keep"
+
+	DefaultDuplicateWindow = time.Second
+
+	DiagnosticDuplicateEvent DiagnosticKind = "duplicate-event"
+)
+
+func ParseDuplicateRule(s string) (DuplicateRule, error) {
+	switch rule := DuplicateRule(s); rule {
+	case DuplicateEarliest, DuplicateLatest, DuplicatePriority, DuplicateKeepAll:
+		return rule, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate rule '%s', expected earliest, latest, priority or keep", s)
+	}
+}
+
+// MergeOptions controls how events reported by more than one station are
+// detected and resolved. Two events are duplicates when they come from
+// different sources, have the same event, competitor and parameters and lie
+// within Window of each other.
+// When Sync is set the sources' clocks are first synchronized to the first
+// source within SyncWindow.
+type MergeOptions struct {
+	Rule       DuplicateRule
+	Window     time.Duration
+	Sync       bool
+	SyncWindow time.Duration
+}
+
+// ExpandEventSources turns the given paths into event sources, replacing each
+// directory with the regular files in it in name order. Sources are named
+// after their file and listed in priority order.
+func ExpandEventSources(paths []string) ([]EventSource, error) {
+	var sources []EventSource
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open events source '%s': %w", path, err)
+		}
+		if !info.IsDir() {
+			sources = append(sources, EventSource{Name: filepath.Base(path), Path: path})
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read events directory '%s': %w", path, err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			sources = append(sources, EventSource{Name: entry.Name(), Path: filepath.Join(path, entry.Name())})
+		}
+	}
+	return sources, nil
+}
+
+// LoadMergedEvents loads every source and merges them into one timeline. A
+// single source is returned as read. Dropped duplicates are reported as
+// warning diagnostics on the dropped event's line. The clock sync estimates
+// are returned when opts.Sync is set.
+func LoadMergedEvents(sources []EventSource, opts MergeOptions) ([]Event, []Diagnostic, []SyncEstimate, error) {
+	var perSource [][]Event
+	var names []string
+	var diagnostics []Diagnostic
+	for _, source := range sources {
+		events, sourceDiagnostics, err := LoadEvents(source.Path)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		diagnostics = append(diagnostics, sourceDiagnostics...)
+		for i := range events {
+			events[i].Station = source.Name
+			events[i].ClockOffset = source.ClockOffset
+			events[i].Timestamp = events[i].Timestamp.Add(source.ClockOffset)
+		}
+		perSource = append(perSource, events)
+		names = append(names, source.Name)
+	}
+	if len(perSource) == 1 {
+		return perSource[0], diagnostics, nil, nil
+	}
+
+	var estimates []SyncEstimate
+	if opts.Sync {
+		estimates = SynchronizeSources(perSource, names, opts.SyncWindow)
+	}
+
+	events, dropped := MergeEvents(perSource, opts)
+	for _, d := range dropped {
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     d.Dropped.Source.File,
+			Line:     d.Dropped.Source.Line,
+			Column:   1,
+			Offset:   d.Dropped.Source.Offset,
+			Raw:      d.Dropped.Line,
+			Kind:     DiagnosticDuplicateEvent,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("duplicate of %s at %s, kept by the %s rule", d.Kept.Source, FormatTime(d.Kept.Timestamp), opts.Rule),
+		})
+	}
+	return events, diagnostics, estimates, nil
+}
+
+// DroppedDuplicate pairs an event removed by MergeEvents with the event kept
+// in its place.
+type DroppedDuplicate struct {
+	Kept    Event
+	Dropped Event
+}
+
+type duplicateKey struct {
+	id           EventID
+	competitorID int
+	params       string
+}
+
+// MergeEvents merges per-source event lists, given in priority order, into a
+// single timeline ordered by time, and resolves duplicates by opts.Rule.
+func MergeEvents(perSource [][]Event, opts MergeOptions) ([]Event, []DroppedDuplicate) {
+	type candidate struct {
+		event    Event
+		priority int
+	}
+	var all []candidate
+	for priority, events := range perSource {
+		for _, event := range events {
+			all = append(all, candidate{event: event, priority: priority})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if !all[i].event.Timestamp.Equal(all[j].event.Timestamp) {
+			return all[i].event.Timestamp.Before(all[j].event.Timestamp)
+		}
+		return all[i].priority < all[j].priority
+	})
+
+	if opts.Rule == DuplicateKeepAll {
+		merged := make([]Event, len(all))
+		for i, c := range all {
+			merged[i] = c.event
+		}
+		return merged, nil
+	}
+	window := opts.Window
+	if window <= 0 {
+		window = DefaultDuplicateWindow
+	}
+
+	// Group each event with the earliest open group of the same key that has
+	// no event from its source yet and started no more than window before it.
+	type group struct {
+		members  []int
+		priority map[int]bool
+	}
+	var groups []*group
+	groupOf := make([]*group, len(all))
+	open := make(map[duplicateKey][]*group)
+	for i, c := range all {
+		key := duplicateKey{c.event.ID, c.event.CompetitorID, c.event.ExtraParamsStr}
+		var match *group
+		for _, g := range open[key] {
+			first := all[g.members[0]].event.Timestamp
+			if c.event.Timestamp.Sub(first) <= window && !g.priority[c.priority] {
+				match = g
+				break
+			}
+		}
+		if match == nil {
+			match = &group{priority: make(map[int]bool)}
+			groups = append(groups, match)
+			open[key] = append(open[key], match)
+		}
+		match.members = append(match.members, i)
+		match.priority[c.priority] = true
+		groupOf[i] = match
+	}
+
+	keep := make(map[*group]int)
+	var dropped []DroppedDuplicate
+	for _, g := range groups {
+		best := g.members[0]
+		for _, m := range g.members[1:] {
+			switch opts.Rule {
+			case DuplicateLatest:
+				best = m
+			case DuplicatePriority:
+				if all[m].priority < all[best].priority {
+					best = m
+				}
+			}
+		}
+		keep[g] = best
+		for _, m := range g.members {
+			if m != best {
+				dropped = append(dropped, DroppedDuplicate{Kept: all[best].event, Dropped: all[m].event})
+			}
+		}
+	}
+
+	merged := make([]Event, 0, len(groups))
+	for i, c := range all {
+		if keep[groupOf[i]] == i {
+			merged = append(merged, c.event)
+		}
+	}
+	return merged, dropped
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMergeEvents(t *testing.T) {
+	finish := func(ms, competitor int, file string) Event {
+		return Event{
+			Timestamp:    testTime(10, 30, 0, ms),
+			ID:           EventEndedMainLap,
+			CompetitorID: competitor,
+			Source:       SourcePos{File: file, Line: competitor},
+		}
+	}
+	start := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1, Source: SourcePos{File: "start", Line: 1}},
+		finish(300, 1, "start"),
+	}
+	finishLine := []Event{
+		finish(100, 1, "finish"),
+		finish(200, 2, "finish"),
+		finish(900, 2, "finish"),
+	}
+
+	tests := []struct {
+		name        string
+		rule        DuplicateRule
+		window      time.Duration
+		wantFiles   []string
+		wantDropped int
+	}{
+		{name: "Earliest", rule: DuplicateEarliest, wantFiles: []string{"start", "finish", "finish", "finish"}, wantDropped: 1},
+		{name: "Latest", rule: DuplicateLatest, wantFiles: []string{"start", "finish", "start", "finish"}, wantDropped: 1},
+		{name: "Priority", rule: DuplicatePriority, wantFiles: []string{"start", "finish", "start", "finish"}, wantDropped: 1},
+		{name: "KeepAll", rule: DuplicateKeepAll, wantFiles: []string{"start", "finish", "finish", "start", "finish"}},
+		{name: "OutsideWindow", rule: DuplicateEarliest, window: 100 * time.Millisecond, wantFiles: []string{"start", "finish", "finish", "start", "finish"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, dropped := MergeEvents([][]Event{start, finishLine}, MergeOptions{Rule: tt.rule, Window: tt.window})
+			if len(merged) != len(tt.wantFiles) {
+				t.Fatalf("Expected %d events, got %d: %+v", len(tt.wantFiles), len(merged), merged)
+			}
+			for i, event := range merged {
+				if event.Source.File != tt.wantFiles[i] {
+					t.Errorf("Event %d: got source %s, want %s", i, event.Source.File, tt.wantFiles[i])
+				}
+				if i > 0 && event.Timestamp.Before(merged[i-1].Timestamp) {
+					t.Errorf("Event %d is out of order", i)
+				}
+			}
+			if len(dropped) != tt.wantDropped {
+				t.Errorf("Expected %d dropped duplicates, got %d", tt.wantDropped, len(dropped))
+			}
+		})
+	}
+}
+
+func TestLoadMergedEvents(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a-start.log":  "[10:00:00.000] 4 1\n",
+		"b-finish.log": "[10:20:00.500] 10 1\n",
+		"c-range.log":  "[10:20:00.000] 10 1\n[10:10:00.000] 5 1 1\n",
+		".hidden":      "garbage\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sources, err := ExpandEventSources([]string{dir})
+	if err != nil {
+		t.Fatalf("ExpandEventSources() error = %v", err)
+	}
+	if len(sources) != 3 || sources[0].Name != "a-start.log" || sources[2].Name != "c-range.log" {
+		t.Fatalf("Unexpected sources: %+v", sources)
+	}
+	sources[1].ClockOffset = -time.Second
+
+	events, diagnostics, _, err := LoadMergedEvents(sources, MergeOptions{Rule: DuplicateEarliest, Window: time.Second})
+	if err != nil {
+		t.Fatalf("LoadMergedEvents() error = %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d: %+v", len(events), events)
+	}
+	lapEnd := events[2]
+	if lapEnd.ID != EventEndedMainLap || lapEnd.Station != "b-finish.log" || lapEnd.ClockOffset != -time.Second {
+		t.Errorf("Expected the corrected finish station's lap end to be kept, got %+v", lapEnd)
+	}
+	if !lapEnd.Timestamp.Equal(testTime(10, 19, 59, 500)) {
+		t.Errorf("Expected clock offset to be applied, got %s", FormatTime(lapEnd.Timestamp))
+	}
+	if len(diagnostics) != 1 || diagnostics[0].Kind != DiagnosticDuplicateEvent || diagnostics[0].IsError() ||
+		filepath.Base(diagnostics[0].File) != "c-range.log" {
+		t.Errorf("Expected one duplicate warning on c-range.log, got %v", diagnostics)
+	}
+}
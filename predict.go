@@ -0,0 +1,400 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+type PredictionOptions struct {
+	Iterations int
+	TopN       int
+	Seed       uint64
+	// Clock is the race clock the prediction is made at. When zero the time of
+	// the latest processed event is used.
+	Clock time.Time
+}
+
+type CompetitorPrediction struct {
+	CompetitorID      int
+	Status            CompetitorStatus
+	WinProbability    float64
+	PodiumProbability float64
+	TopNProbability   float64
+	ExpectedFinish    time.Duration
+	FinishP10         time.Duration
+	FinishP50         time.Duration
+	FinishP90         time.Duration
+}
+
+// performanceModel holds what is known about how a competitor skis and shoots,
+// taken from the laps and stages already completed.
+type performanceModel struct {
+	skiPerMeter      float64
+	skiStdDev        float64
+	hitProbability   float64
+	rangeTime        float64
+	penaltyLoopTime  float64
+	hasSkiHistory    bool
+	hasRangeHistory  bool
+	hasPenaltyLoops  bool
+	hasShootingShots bool
+}
+
+func lapSkiSeconds(lap LapRecord) float64 {
+	ski := lap.LapDuration
+	for _, sr := range lap.ShootingData {
+		ski -= sr.ExitTime.Sub(sr.EntryTime)
+	}
+	if !lap.PenaltyEntryTime.IsZero() && !lap.PenaltyExitTime.IsZero() {
+		ski -= lap.PenaltyExitTime.Sub(lap.PenaltyEntryTime)
+	}
+	return ski.Seconds()
+}
+
+func buildPerformanceModel(c *Competitor, config *Config) performanceModel {
+	var m performanceModel
+	var skiTimes []float64
+	var rangeTotal time.Duration
+	var stages, hits, shots, loops int
+	var penaltyTotal time.Duration
+
+	for _, lap := range c.LapsData {
+		if !lap.EndTime.IsZero() && !lap.StartTime.IsZero() {
+			duration := lap.EndTime.Sub(lap.StartTime)
+			lap.LapDuration = duration
+			if ski := lapSkiSeconds(lap); ski > 0 {
+				skiTimes = append(skiTimes, ski/float64(config.LapLen))
+			}
+		}
+		for _, sr := range lap.ShootingData {
+			rangeTotal += sr.ExitTime.Sub(sr.EntryTime)
+			stages++
+			hits += sr.Hits
+			shots += sr.Shots
+		}
+		if !lap.PenaltyEntryTime.IsZero() && !lap.PenaltyExitTime.IsZero() && lap.PenaltiesServed > 0 {
+			penaltyTotal += lap.PenaltyExitTime.Sub(lap.PenaltyEntryTime)
+			loops += lap.PenaltiesServed
+		}
+	}
+
+	if len(skiTimes) > 0 {
+		m.hasSkiHistory = true
+		m.skiPerMeter, m.skiStdDev = meanStdDev(skiTimes)
+	}
+	if stages > 0 {
+		m.hasRangeHistory = true
+		m.rangeTime = rangeTotal.Seconds() / float64(stages)
+	}
+	if shots > 0 {
+		m.hasShootingShots = true
+		m.hitProbability = (float64(hits) + 1) / (float64(shots) + 2)
+	}
+	if loops > 0 {
+		m.hasPenaltyLoops = true
+		m.penaltyLoopTime = penaltyTotal.Seconds() / float64(loops)
+	}
+	return m
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	if len(values) > 1 {
+		variance /= float64(len(values) - 1)
+	}
+	return mean, math.Sqrt(variance)
+}
+
+// fillFromField replaces whatever a competitor has no history for with the
+// average of the field, or with generic defaults when nobody has history yet.
+func (m *performanceModel) fillFromField(field performanceModel, config *Config) {
+	if !m.hasSkiHistory {
+		m.skiPerMeter, m.skiStdDev = field.skiPerMeter, field.skiStdDev
+	}
+	if m.skiPerMeter <= 0 {
+		m.skiPerMeter = 1 / 6.0
+	}
+	if m.skiStdDev <= 0 {
+		m.skiStdDev = m.skiPerMeter * 0.03
+	}
+	if !m.hasRangeHistory {
+		m.rangeTime = field.rangeTime
+	}
+	if m.rangeTime <= 0 {
+		m.rangeTime = 30
+	}
+	if !m.hasShootingShots {
+		m.hitProbability = field.hitProbability
+	}
+	if m.hitProbability <= 0 {
+		m.hitProbability = 0.8
+	}
+	if !m.hasPenaltyLoops {
+		m.penaltyLoopTime = field.penaltyLoopTime
+	}
+	if m.penaltyLoopTime <= 0 {
+		m.penaltyLoopTime = float64(config.PenaltyLen) * m.skiPerMeter * 1.1
+	}
+}
+
+func averageModel(models []performanceModel) performanceModel {
+	var field performanceModel
+	var ski, skiDev, rangeTime, hit, loop []float64
+	for _, m := range models {
+		if m.hasSkiHistory {
+			ski = append(ski, m.skiPerMeter)
+			skiDev = append(skiDev, m.skiStdDev)
+		}
+		if m.hasRangeHistory {
+			rangeTime = append(rangeTime, m.rangeTime)
+		}
+		if m.hasShootingShots {
+			hit = append(hit, m.hitProbability)
+		}
+		if m.hasPenaltyLoops {
+			loop = append(loop, m.penaltyLoopTime)
+		}
+	}
+	if len(ski) > 0 {
+		field.skiPerMeter, _ = meanStdDev(ski)
+		field.skiStdDev, _ = meanStdDev(skiDev)
+	}
+	if len(rangeTime) > 0 {
+		field.rangeTime, _ = meanStdDev(rangeTime)
+	}
+	if len(hit) > 0 {
+		field.hitProbability, _ = meanStdDev(hit)
+	}
+	if len(loop) > 0 {
+		field.penaltyLoopTime, _ = meanStdDev(loop)
+	}
+	return field
+}
+
+type contender struct {
+	competitor *Competitor
+	model      performanceModel
+	fixed      time.Duration
+	finished   bool
+	samples    []time.Duration
+}
+
+// inContention reports whether a competitor can still finish: anyone on the
+// course, finished, or drawn to start after the prediction clock.
+func inContention(c *Competitor, clock time.Time) bool {
+	switch c.Status {
+	case StatusNotFinished, StatusNotStarted, StatusDisqualified, StatusLapped:
+		return false
+	case StatusRegistered, StatusScheduled:
+		return !c.ScheduledStartTime.IsZero() && !c.ScheduledStartTime.Before(clock)
+	}
+	return true
+}
+
+func (m performanceModel) sampleStage(rng *rand.Rand) float64 {
+	seconds := m.rangeTime * (1 + rng.NormFloat64()*0.1)
+	for shot := 0; shot < TargetsPerStage; shot++ {
+		if rng.Float64() >= m.hitProbability {
+			seconds += m.penaltyLoopTime
+		}
+	}
+	return math.Max(seconds, 0)
+}
+
+func (m performanceModel) sampleSki(rng *rand.Rand, meters float64) float64 {
+	perMeter := math.Max(m.skiPerMeter+rng.NormFloat64()*m.skiStdDev, m.skiPerMeter*0.5)
+	return perMeter * meters
+}
+
+// sampleFinish returns one possible total race time for a competitor still on
+// the course (or yet to start) at the given clock.
+func (ct *contender) sampleFinish(rng *rand.Rand, config *Config, clock time.Time) time.Duration {
+	c := ct.competitor
+	m := ct.model
+	if c.ActualStartTime.IsZero() {
+		var total float64
+		for lap := 0; lap < config.Laps; lap++ {
+			total += m.sampleSki(rng, float64(config.LapLen))
+			for line := 0; line < config.FiringLines; line++ {
+				total += m.sampleStage(rng)
+			}
+		}
+		return time.Duration(total * float64(time.Second))
+	}
+
+	elapsed := clock.Sub(c.ActualStartTime)
+	completed := lapsCompleted(c)
+	var remaining float64
+
+	if completed < config.Laps && completed < len(c.LapsData) {
+		lap := c.LapsData[completed]
+		inLap := clock.Sub(lap.StartTime).Seconds()
+		spentOff := 0.0
+		for _, sr := range lap.ShootingData {
+			spentOff += sr.ExitTime.Sub(sr.EntryTime).Seconds()
+		}
+		if !lap.PenaltyEntryTime.IsZero() && !lap.PenaltyExitTime.IsZero() {
+			spentOff += lap.PenaltyExitTime.Sub(lap.PenaltyEntryTime).Seconds()
+		}
+		remaining += math.Max(m.sampleSki(rng, float64(config.LapLen))-(inLap-spentOff), 0)
+		for line := len(lap.ShootingData); line < config.FiringLines; line++ {
+			remaining += m.sampleStage(rng)
+		}
+		completed++
+	}
+	for lap := completed; lap < config.Laps; lap++ {
+		remaining += m.sampleSki(rng, float64(config.LapLen))
+		for line := 0; line < config.FiringLines; line++ {
+			remaining += m.sampleStage(rng)
+		}
+	}
+	return elapsed + time.Duration(remaining*float64(time.Second)) + c.TimeAdjustment
+}
+
+// PredictOutcome finishes the race from the current simulation state many
+// times over and reports how often each competitor wins, reaches the podium
+// or the top N, along with the distribution of their finish times.
+func PredictOutcome(sim *Simulation, opts PredictionOptions) []CompetitorPrediction {
+	if opts.Iterations <= 0 {
+		opts.Iterations = 10000
+	}
+	if opts.TopN <= 0 {
+		opts.TopN = 6
+	}
+	clock := opts.Clock
+	if clock.IsZero() {
+		for _, c := range sim.Competitors {
+			if c.LastEventTime.After(clock) {
+				clock = c.LastEventTime
+			}
+		}
+	}
+
+	var contenders []*contender
+	var models []performanceModel
+	for _, c := range SortCompetitors(sim.Competitors, sim.Config) {
+		if !inContention(c, clock) {
+			continue
+		}
+		ct := &contender{competitor: c, model: buildPerformanceModel(c, sim.Config)}
+		if hasFinished(c) {
+			ct.finished = true
+			ct.fixed = RaceTime(c)
+		}
+		contenders = append(contenders, ct)
+		models = append(models, ct.model)
+	}
+	field := averageModel(models)
+	for _, ct := range contenders {
+		ct.model.fillFromField(field, sim.Config)
+		ct.samples = make([]time.Duration, 0, opts.Iterations)
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	wins := make([]int, len(contenders))
+	podiums := make([]int, len(contenders))
+	topN := make([]int, len(contenders))
+	order := make([]int, len(contenders))
+	times := make([]time.Duration, len(contenders))
+
+	for iteration := 0; iteration < opts.Iterations; iteration++ {
+		for i, ct := range contenders {
+			if ct.finished {
+				times[i] = ct.fixed
+			} else {
+				times[i] = ct.sampleFinish(rng, sim.Config, clock)
+			}
+			ct.samples = append(ct.samples, times[i])
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool {
+			if times[order[a]] != times[order[b]] {
+				return times[order[a]] < times[order[b]]
+			}
+			return contenders[order[a]].competitor.ID < contenders[order[b]].competitor.ID
+		})
+		for rank, i := range order {
+			if rank == 0 {
+				wins[i]++
+			}
+			if rank < 3 {
+				podiums[i]++
+			}
+			if rank < opts.TopN {
+				topN[i]++
+			}
+		}
+	}
+
+	predictions := make([]CompetitorPrediction, 0, len(contenders))
+	n := float64(opts.Iterations)
+	for i, ct := range contenders {
+		sort.Slice(ct.samples, func(a, b int) bool { return ct.samples[a] < ct.samples[b] })
+		var sum time.Duration
+		for _, sample := range ct.samples {
+			sum += sample
+		}
+		predictions = append(predictions, CompetitorPrediction{
+			CompetitorID:      ct.competitor.ID,
+			Status:            ct.competitor.Status,
+			WinProbability:    float64(wins[i]) / n,
+			PodiumProbability: float64(podiums[i]) / n,
+			TopNProbability:   float64(topN[i]) / n,
+			ExpectedFinish:    sum / time.Duration(len(ct.samples)),
+			FinishP10:         percentile(ct.samples, 0.1),
+			FinishP50:         percentile(ct.samples, 0.5),
+			FinishP90:         percentile(ct.samples, 0.9),
+		})
+	}
+
+	sort.SliceStable(predictions, func(i, j int) bool {
+		p1, p2 := predictions[i], predictions[j]
+		if p1.WinProbability != p2.WinProbability {
+			return p1.WinProbability > p2.WinProbability
+		}
+		if p1.PodiumProbability != p2.PodiumProbability {
+			return p1.PodiumProbability > p2.PodiumProbability
+		}
+		return p1.ExpectedFinish < p2.ExpectedFinish
+	})
+	return predictions
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Round(p * float64(len(sorted)-1)))
+	return sorted[idx]
+}
+
+func WritePredictions(w io.Writer, predictions []CompetitorPrediction, topN int) {
+	fmt.Fprintln(w, "Predicted outcome")
+	fmt.Fprintln(w, "-----------------")
+	headerFormat := "%-5s %-12s %-8s %-8s %-8s %-13s %-13s %-13s %-13s\n"
+	fmt.Fprintf(w, headerFormat, "ID", "Status", "Win", "Podium", fmt.Sprintf("Top %d", topN), "Expected", "P10", "P50", "P90")
+	for _, p := range predictions {
+		fmt.Fprintf(w, headerFormat,
+			fmt.Sprint(p.CompetitorID),
+			p.Status,
+			fmt.Sprintf("%.1f%%", p.WinProbability*100),
+			fmt.Sprintf("%.1f%%", p.PodiumProbability*100),
+			fmt.Sprintf("%.1f%%", p.TopNProbability*100),
+			FormatDuration(p.ExpectedFinish),
+			FormatDuration(p.FinishP10),
+			FormatDuration(p.FinishP50),
+			FormatDuration(p.FinishP90))
+	}
+}
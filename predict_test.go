@@ -0,0 +1,94 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPredictOutcome(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Laps = 2
+	sim := NewSimulation(cfg)
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 3},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 4},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 10, 0), ID: EventEndedMainLap, CompetitorID: 2},
+		{Timestamp: testTime(10, 8, 0, 0), ID: EventEndedMainLap, CompetitorID: 3},
+		{Timestamp: testTime(10, 9, 0, 0), ID: EventCannotContinue, CompetitorID: 4, Comment: "Injured"},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+	}
+	for _, event := range events {
+		sim.Step(event)
+	}
+
+	opts := PredictionOptions{Iterations: 2000, TopN: 2, Seed: 9, Clock: testTime(10, 10, 0, 0)}
+	predictions := PredictOutcome(sim, opts)
+	if len(predictions) != 3 {
+		t.Fatalf("Expected 3 competitors in contention, got %d", len(predictions))
+	}
+
+	byID := make(map[int]CompetitorPrediction)
+	var totalWin float64
+	for _, p := range predictions {
+		byID[p.CompetitorID] = p
+		totalWin += p.WinProbability
+		if p.FinishP10 > p.FinishP50 || p.FinishP50 > p.FinishP90 {
+			t.Errorf("Competitor %d percentiles out of order: %+v", p.CompetitorID, p)
+		}
+	}
+	if math.Abs(totalWin-1) > 1e-9 {
+		t.Errorf("Win probabilities should sum to 1, got %f", totalWin)
+	}
+	if _, ok := byID[4]; ok {
+		t.Errorf("DNF competitor should not be in contention")
+	}
+
+	leader := byID[1]
+	if leader.ExpectedFinish != 10*time.Minute || leader.FinishP10 != leader.FinishP90 {
+		t.Errorf("Finished competitor should have a fixed time: %+v", leader)
+	}
+	if byID[3].WinProbability > 0.01 {
+		t.Errorf("A competitor a full minute per lap slower should almost never win: %+v", byID[3])
+	}
+	if byID[2].PodiumProbability != 1 || byID[2].TopNProbability < byID[3].TopNProbability {
+		t.Errorf("Competitor 2 prediction unexpected: %+v", byID[2])
+	}
+
+	again := PredictOutcome(sim, opts)
+	for i := range predictions {
+		if predictions[i] != again[i] {
+			t.Fatalf("Same seed produced different predictions: %+v vs %+v", predictions[i], again[i])
+		}
+	}
+}
+
+func TestPredictOutcome_TimePenalty(t *testing.T) {
+	cfg := createTestConfig()
+	sim := NewSimulation(cfg)
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 11, 0, 0), ID: EventEndedMainLap, CompetitorID: 2},
+		{Timestamp: testTime(10, 12, 0, 0), ID: EventTimePenalty, CompetitorID: 1, Penalty: 2 * time.Minute},
+	}
+	for _, event := range events {
+		sim.Step(event)
+	}
+
+	predictions := PredictOutcome(sim, PredictionOptions{Iterations: 10, TopN: 1, Seed: 1, Clock: testTime(10, 12, 0, 0)})
+	if len(predictions) != 2 {
+		t.Fatalf("Expected 2 competitors in contention, got %d", len(predictions))
+	}
+	if predictions[0].CompetitorID != 2 || predictions[0].WinProbability != 1 {
+		t.Errorf("Competitor 2 should win once the penalty is added: %+v", predictions[0])
+	}
+	if predictions[1].ExpectedFinish != 12*time.Minute {
+		t.Errorf("Penalised finish: got %v, want 12m", predictions[1].ExpectedFinish)
+	}
+}
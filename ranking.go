@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const DefaultTimingPrecision = 100 * time.Millisecond
+
+type RankEntry struct {
+	Competitor *Competitor
+	Rank       int
+	Tied       bool
+	RaceTime   time.Duration
+	Behind     time.Duration
+
+	timing TimingPolicy
+}
+
+func hasFinished(c *Competitor) bool {
+	return c.Status == StatusCompleted && !c.FinishTime.IsZero()
+}
+
+func statusOrder(s CompetitorStatus) int {
+	switch s {
+	case StatusNotFinished:
+		return 1
+	case StatusNotStarted:
+		return 2
+	case StatusDisqualified:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// StatusLabel returns the short code used for non-finishers in result lists.
+func StatusLabel(s CompetitorStatus) string {
+	switch s {
+	case StatusNotFinished:
+		return "DNF"
+	case StatusNotStarted:
+		return "DNS"
+	case StatusDisqualified:
+		return "DSQ"
+	case StatusLapped:
+		return "LAP"
+	default:
+		return string(s)
+	}
+}
+
+// RaceTime is the time from start to finish including any time added or
+// removed by officials.
+func RaceTime(c *Competitor) time.Duration {
+	return c.FinishTime.Sub(c.ActualStartTime) + c.TimeAdjustment
+}
+
+// OfficialRaceTime is the race time under the timing policy, which is what
+// ranks are decided on.
+func OfficialRaceTime(c *Competitor, timing TimingPolicy) time.Duration {
+	return timing.Apply(RaceTime(c))
+}
+
+// competitorLess orders finishers by official race time, then lapped
+// competitors by laps completed and the rank they were pulled at, then
+// everyone else as DNF, DNS, DSQ and any other status. Equal times and
+// statuses are listed by competitor ID.
+func competitorLess(c1, c2 *Competitor, timing TimingPolicy) bool {
+	c1Finished := hasFinished(c1)
+	c2Finished := hasFinished(c2)
+
+	if c1Finished && c2Finished {
+		t1 := OfficialRaceTime(c1, timing)
+		t2 := OfficialRaceTime(c2, timing)
+		if t1 != t2 {
+			return t1 < t2
+		}
+		return c1.ID < c2.ID
+	}
+	if c1Finished {
+		return true
+	}
+	if c2Finished {
+		return false
+	}
+
+	c1Lapped := c1.Status == StatusLapped
+	c2Lapped := c2.Status == StatusLapped
+	if c1Lapped && c2Lapped {
+		if l1, l2 := lapsCompleted(c1), lapsCompleted(c2); l1 != l2 {
+			return l1 > l2
+		}
+		if c1.LappedRank != c2.LappedRank {
+			return c1.LappedRank < c2.LappedRank
+		}
+		return c1.ID < c2.ID
+	}
+	if c1Lapped != c2Lapped {
+		return c1Lapped
+	}
+
+	if statusOrder(c1.Status) != statusOrder(c2.Status) {
+		return statusOrder(c1.Status) < statusOrder(c2.Status)
+	}
+	return c1.ID < c2.ID
+}
+
+func SortCompetitors(competitors map[int]*Competitor, config *Config) []*Competitor {
+	var sortedCompetitors []*Competitor
+	for _, c := range competitors {
+		sortedCompetitors = append(sortedCompetitors, c)
+	}
+
+	timing := config.Timing()
+	sort.Slice(sortedCompetitors, func(i, j int) bool {
+		return competitorLess(sortedCompetitors[i], sortedCompetitors[j], timing)
+	})
+	return sortedCompetitors
+}
+
+// RankSorted assigns ranks to an already sorted result list. Finishers with
+// the same official time share a rank and the next rank is skipped, as in
+// 1, =2, =2, 4. Lapped competitors are ranked after the finishers without a
+// time; other non-finishers are not ranked.
+func RankSorted(sorted []*Competitor, timing TimingPolicy) []RankEntry {
+	entries := make([]RankEntry, len(sorted))
+	var leader time.Duration
+	for i, c := range sorted {
+		entries[i].Competitor = c
+		if c.Status == StatusLapped {
+			entries[i].Rank = i + 1
+			continue
+		}
+		if !hasFinished(c) {
+			continue
+		}
+		entries[i].RaceTime = OfficialRaceTime(c, timing)
+		entries[i].timing = timing
+		if i == 0 {
+			leader = entries[i].RaceTime
+		}
+		entries[i].Behind = entries[i].RaceTime - leader
+		entries[i].Rank = i + 1
+		if i > 0 && hasFinished(sorted[i-1]) && entries[i-1].RaceTime == entries[i].RaceTime {
+			entries[i].Rank = entries[i-1].Rank
+			entries[i].Tied = true
+			entries[i-1].Tied = true
+		}
+	}
+	return entries
+}
+
+func RankCompetitors(competitors map[int]*Competitor, config *Config) []RankEntry {
+	return RankSorted(SortCompetitors(competitors, config), config.Timing())
+}
+
+// RankString renders the rank as shown in result lists: "3", "=3" for a tie
+// or the status code of a non-finisher.
+func (e RankEntry) RankString() string {
+	if e.Rank == 0 {
+		return StatusLabel(e.Competitor.Status)
+	}
+	return formatRank(e.Rank, e.Tied)
+}
+
+func (e RankEntry) BehindString() string {
+	if e.Rank == 0 || e.Behind == 0 {
+		return ""
+	}
+	return fmt.Sprintf("+%s", e.timing.Format(e.Behind))
+}
+
+func formatRank(rank int, tied bool) string {
+	if rank == 0 {
+		return "-"
+	}
+	if tied {
+		return "=" + strconv.Itoa(rank)
+	}
+	return strconv.Itoa(rank)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRankCompetitors(t *testing.T) {
+	dnf := NewCompetitor(7)
+	dnf.Status = StatusNotFinished
+	dns := NewCompetitor(2)
+	dns.Status = StatusNotStarted
+	dsq := NewCompetitor(1)
+	dsq.Status = StatusDisqualified
+
+	competitors := map[int]*Competitor{
+		1: dsq,
+		2: dns,
+		3: finishedCompetitor(3, 25*time.Minute+120*time.Millisecond, nil),
+		4: finishedCompetitor(4, 25*time.Minute+160*time.Millisecond, nil),
+		5: finishedCompetitor(5, 24*time.Minute+59*time.Second+500*time.Millisecond, nil),
+		6: finishedCompetitor(6, 25*time.Minute+910*time.Millisecond, nil),
+		7: dnf,
+	}
+
+	tests := []struct {
+		name       string
+		precision  time.Duration
+		round      bool
+		wantIDs    []int
+		wantRanks  []string
+		wantBehind []string
+	}{
+		{
+			name:       "Tenths",
+			precision:  100 * time.Millisecond,
+			wantIDs:    []int{5, 3, 4, 6, 7, 2, 1},
+			wantRanks:  []string{"1", "=2", "=2", "4", "DNF", "DNS", "DSQ"},
+			wantBehind: []string{"", "+00:00:00.6", "+00:00:00.6", "+00:00:01.4", "", "", ""},
+		},
+		{
+			name:       "Seconds",
+			precision:  time.Second,
+			wantIDs:    []int{5, 3, 4, 6, 7, 2, 1},
+			wantRanks:  []string{"1", "=2", "=2", "=2", "DNF", "DNS", "DSQ"},
+			wantBehind: []string{"", "+00:00:01", "+00:00:01", "+00:00:01", "", "", ""},
+		},
+		{
+			name:       "RoundedTenths",
+			precision:  100 * time.Millisecond,
+			round:      true,
+			wantIDs:    []int{5, 3, 4, 6, 7, 2, 1},
+			wantRanks:  []string{"1", "2", "3", "4", "DNF", "DNS", "DSQ"},
+			wantBehind: []string{"", "+00:00:00.6", "+00:00:00.7", "+00:00:01.4", "", "", ""},
+		},
+		{
+			name:       "Milliseconds",
+			precision:  time.Millisecond,
+			wantIDs:    []int{5, 3, 4, 6, 7, 2, 1},
+			wantRanks:  []string{"1", "2", "3", "4", "DNF", "DNS", "DSQ"},
+			wantBehind: []string{"", "+00:00:00.620", "+00:00:00.660", "+00:00:01.410", "", "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createTestConfig()
+			cfg.TimingPrecision = tt.precision
+			if tt.round {
+				cfg.TimingRounding = RoundingRound
+			}
+			ranking := RankCompetitors(competitors, cfg)
+			if len(ranking) != len(tt.wantIDs) {
+				t.Fatalf("Expected %d entries, got %d", len(tt.wantIDs), len(ranking))
+			}
+			for i, entry := range ranking {
+				if entry.Competitor.ID != tt.wantIDs[i] {
+					t.Errorf("Entry %d ID: got %d, want %d", i, entry.Competitor.ID, tt.wantIDs[i])
+				}
+				if got := entry.RankString(); got != tt.wantRanks[i] {
+					t.Errorf("Entry %d rank: got %s, want %s", i, got, tt.wantRanks[i])
+				}
+				if got := entry.BehindString(); got != tt.wantBehind[i] {
+					t.Errorf("Entry %d behind: got %s, want %s", i, got, tt.wantBehind[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type LegSplit struct {
+	Leg          int
+	CompetitorID int
+	Status       CompetitorStatus
+	StartTime    time.Time
+	FinishTime   time.Time
+	Duration     time.Duration
+	Adjustment   time.Duration
+	LapDurations []time.Duration
+	Hits         int
+	Shots        int
+	SparesUsed   int
+	Penalties    int
+}
+
+type TeamStanding struct {
+	Team          string
+	Legs          []LegSplit
+	LegsCompleted int
+	Finished      bool
+	TotalTime     time.Duration
+	Status        CompetitorStatus
+	Rank          int
+	Tied          bool
+}
+
+func newLegSplit(c *Competitor, leg int) LegSplit {
+	split := LegSplit{
+		Leg:          leg,
+		CompetitorID: c.ID,
+		Status:       c.Status,
+		StartTime:    c.ActualStartTime,
+		FinishTime:   c.FinishTime,
+		Hits:         c.TotalHits,
+		Shots:        c.TotalShots,
+		Penalties:    c.TotalPenaltiesServed,
+		Adjustment:   c.TimeAdjustment,
+	}
+	if hasFinished(c) {
+		split.Duration = RaceTime(c)
+	}
+	for _, lap := range c.LapsData {
+		split.LapDurations = append(split.LapDurations, lap.LapDuration)
+		for _, sr := range lap.ShootingData {
+			split.SparesUsed += sr.SparesUsed
+		}
+	}
+	return split
+}
+
+// ComputeTeamStandings builds relay standings from competitors that carry the
+// "team" and "leg" roster attributes. A team is finished once every leg up to
+// the highest leg number seen for any team has completed its laps.
+func ComputeTeamStandings(competitors map[int]*Competitor, config *Config) []TeamStanding {
+	timing := config.Timing()
+	teams := make(map[string]*TeamStanding)
+	maxLeg := 0
+
+	for _, c := range competitors {
+		team, ok := c.Attributes["team"]
+		if !ok || team == "" {
+			continue
+		}
+		leg, err := strconv.Atoi(c.Attributes["leg"])
+		if err != nil || leg <= 0 {
+			continue
+		}
+		if leg > maxLeg {
+			maxLeg = leg
+		}
+
+		standing, ok := teams[team]
+		if !ok {
+			standing = &TeamStanding{Team: team}
+			teams[team] = standing
+		}
+		standing.Legs = append(standing.Legs, newLegSplit(c, leg))
+	}
+
+	standings := make([]TeamStanding, 0, len(teams))
+	for _, standing := range teams {
+		sort.Slice(standing.Legs, func(i, j int) bool {
+			return standing.Legs[i].Leg < standing.Legs[j].Leg
+		})
+
+		standing.Status = StatusRacing
+		for _, split := range standing.Legs {
+			if split.Status != StatusCompleted || split.FinishTime.IsZero() {
+				if split.Status == StatusNotFinished || split.Status == StatusNotStarted || split.Status == StatusDisqualified {
+					standing.Status = split.Status
+				}
+				break
+			}
+			standing.LegsCompleted++
+		}
+
+		if standing.LegsCompleted == maxLeg && len(standing.Legs) == maxLeg {
+			first := standing.Legs[0]
+			last := standing.Legs[len(standing.Legs)-1]
+			total := last.FinishTime.Sub(first.StartTime)
+			for _, split := range standing.Legs {
+				total += split.Adjustment
+			}
+			standing.Finished = true
+			standing.Status = StatusCompleted
+			standing.TotalTime = timing.Apply(total)
+		}
+		standings = append(standings, *standing)
+	}
+
+	sort.Slice(standings, func(i, j int) bool {
+		t1, t2 := standings[i], standings[j]
+		if t1.Finished != t2.Finished {
+			return t1.Finished
+		}
+		if t1.Finished && t1.TotalTime != t2.TotalTime {
+			return t1.TotalTime < t2.TotalTime
+		}
+		if t1.LegsCompleted != t2.LegsCompleted {
+			return t1.LegsCompleted > t2.LegsCompleted
+		}
+		if statusOrder(t1.Status) != statusOrder(t2.Status) {
+			return statusOrder(t1.Status) < statusOrder(t2.Status)
+		}
+		return t1.Team < t2.Team
+	})
+
+	// Finished teams share a rank when their official times are equal, the
+	// same way competitors do.
+	for i := range standings {
+		if !standings[i].Finished {
+			continue
+		}
+		standings[i].Rank = i + 1
+		if i > 0 && standings[i-1].Finished && standings[i-1].TotalTime == standings[i].TotalTime {
+			standings[i].Rank = standings[i-1].Rank
+			standings[i].Tied = true
+			standings[i-1].Tied = true
+		}
+	}
+	return standings
+}
+
+// RankString renders the team's rank as "3" or "=3" for a tie, and "-" for a
+// team that has not finished.
+func (t TeamStanding) RankString() string {
+	return formatRank(t.Rank, t.Tied)
+}
+
+func (t TeamStanding) ResultString(timing TimingPolicy) string {
+	if t.Finished {
+		return timing.Format(t.TotalTime)
+	}
+	return fmt.Sprintf("[%s]", t.Status)
+}
+
+func GenerateTeamReport(w io.Writer, competitors map[int]*Competitor, config *Config) {
+	timing := config.Timing()
+	fmt.Fprintln(w, "Team standings")
+	fmt.Fprintln(w, "--------------")
+
+	headerFormat := "%-5s %-15s %-15s %-5s %-15s %-10s %-8s\n"
+	fmt.Fprintf(w, headerFormat, "Rank", "Team", "Result/Status", "Leg", "Leg Time", "Shooting", "Spares")
+
+	for _, standing := range ComputeTeamStandings(competitors, config) {
+		rankStr := standing.RankString()
+		for i, split := range standing.Legs {
+			legTime := fmt.Sprintf("[%s]", split.Status)
+			if split.Duration > 0 {
+				legTime = timing.Format(split.Duration)
+			}
+			if i == 0 {
+				fmt.Fprintf(w, headerFormat, rankStr, standing.Team, standing.ResultString(timing), strconv.Itoa(split.Leg), legTime, fmt.Sprintf("%d/%d", split.Hits, split.Shots), strconv.Itoa(split.SparesUsed))
+			} else {
+				fmt.Fprintf(w, headerFormat, "", "", "", strconv.Itoa(split.Leg), legTime, fmt.Sprintf("%d/%d", split.Hits, split.Shots), strconv.Itoa(split.SparesUsed))
+			}
+		}
+	}
+}
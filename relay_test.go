@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulation_RelayHandOffAndStandings(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mode = ModeRelay
+	sim := NewSimulation(cfg)
+	sim.ApplyRoster(Roster{
+		1: {"team": "NOR", "leg": "1"},
+		2: {"team": "NOR", "leg": "2"},
+		3: {"team": "FRA", "leg": "1"},
+		4: {"team": "FRA", "leg": "2"},
+	})
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 3},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+		{Timestamp: testTime(10, 5, 1, 0), ID: EventTargetHit, CompetitorID: 1, Target: 1},
+		{Timestamp: testTime(10, 5, 2, 0), ID: EventTargetHit, CompetitorID: 1, Target: 2},
+		{Timestamp: testTime(10, 5, 3, 0), ID: EventTargetHit, CompetitorID: 1, Target: 3},
+		{Timestamp: testTime(10, 5, 30, 0), ID: EventLeftFiringRange, CompetitorID: 1},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventRelayHandOff, CompetitorID: 1, NextCompetitorID: 2},
+		{Timestamp: testTime(10, 11, 0, 0), ID: EventEndedMainLap, CompetitorID: 3},
+		{Timestamp: testTime(10, 11, 0, 0), ID: EventRelayHandOff, CompetitorID: 3, NextCompetitorID: 4},
+		{Timestamp: testTime(10, 15, 0, 0), ID: EventCannotContinue, CompetitorID: 4, Comment: "Broken ski"},
+		{Timestamp: testTime(10, 19, 0, 0), ID: EventEndedMainLap, CompetitorID: 2},
+	}
+
+	sim.Run(events)
+	sim.FinalizeResults()
+
+	leg1 := sim.Competitors[1]
+	if leg1.TotalShots != 7 {
+		t.Errorf("Leg 1 TotalShots: got %d, want 7", leg1.TotalShots)
+	}
+	sr := leg1.LapsData[0].ShootingData[0]
+	if sr.SparesUsed != 2 || sr.PenaltiesIncurred != 2 {
+		t.Errorf("Leg 1 shooting: got spares %d penalties %d, want 2 and 2", sr.SparesUsed, sr.PenaltiesIncurred)
+	}
+
+	leg2 := sim.Competitors[2]
+	if leg2.Status != StatusCompleted {
+		t.Errorf("Leg 2 status: got %s, want %s", leg2.Status, StatusCompleted)
+	}
+	if !leg2.ActualStartTime.Equal(testTime(10, 10, 0, 0)) {
+		t.Errorf("Leg 2 start: got %v, want hand-off time", leg2.ActualStartTime)
+	}
+
+	standings := ComputeTeamStandings(sim.Competitors, cfg)
+	if len(standings) != 2 {
+		t.Fatalf("Expected 2 teams, got %d", len(standings))
+	}
+	nor, fra := standings[0], standings[1]
+	if nor.Team != "NOR" || !nor.Finished || nor.TotalTime != 19*time.Minute {
+		t.Errorf("NOR standing mismatch: %+v", nor)
+	}
+	if len(nor.Legs) != 2 || nor.Legs[0].Duration != 10*time.Minute || nor.Legs[1].Duration != 9*time.Minute {
+		t.Errorf("NOR leg splits mismatch: %+v", nor.Legs)
+	}
+	if fra.Team != "FRA" || fra.Finished || fra.Status != StatusNotFinished || fra.LegsCompleted != 1 {
+		t.Errorf("FRA standing mismatch: %+v", fra)
+	}
+}
+
+func TestSimulation_RelaySpareRoundEvents(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mode = ModeRelay
+	sim := NewSimulation(cfg)
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+	}
+	shotTime := testTime(10, 5, 1, 0)
+	for i := 1; i <= 5; i++ {
+		events = append(events, Event{Timestamp: shotTime, ID: EventShotFired, CompetitorID: 1})
+		if i <= 3 {
+			events = append(events, Event{Timestamp: shotTime, ID: EventTargetHit, CompetitorID: 1, Target: i})
+		}
+		shotTime = shotTime.Add(2 * time.Second)
+	}
+	for i := 0; i < 2; i++ {
+		events = append(events,
+			Event{Timestamp: shotTime, ID: EventSpareLoaded, CompetitorID: 1},
+			Event{Timestamp: shotTime.Add(time.Second), ID: EventShotFired, CompetitorID: 1},
+		)
+		shotTime = shotTime.Add(5 * time.Second)
+	}
+	events = append(events,
+		Event{Timestamp: shotTime, ID: EventTargetHit, CompetitorID: 1, Target: 4},
+		Event{Timestamp: shotTime.Add(5 * time.Second), ID: EventLeftFiringRange, CompetitorID: 1},
+	)
+
+	sim.Run(events)
+	sim.FinalizeResults()
+
+	c := sim.Competitors[1]
+	if c.TotalShots != 7 || c.TotalHits != 4 {
+		t.Errorf("Shooting totals: got %d/%d, want 4/7", c.TotalHits, c.TotalShots)
+	}
+	sr := c.LapsData[0].ShootingData[0]
+	if sr.Shots != 7 || sr.SparesUsed != 2 || sr.Hits != 4 || sr.PenaltiesIncurred != 1 {
+		t.Errorf("ShootingRecord mismatch: %+v", sr)
+	}
+	if c.CurrentLapTempData.PenaltiesToServe != 1 {
+		t.Errorf("PenaltiesToServe: got %d, want 1", c.CurrentLapTempData.PenaltiesToServe)
+	}
+}
+
+func TestSimulation_RelaySparesWithoutShotEvents(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mode = ModeRelay
+	sim := NewSimulation(cfg)
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+		{Timestamp: testTime(10, 5, 2, 0), ID: EventTargetHit, CompetitorID: 1, Target: 1},
+		{Timestamp: testTime(10, 5, 4, 0), ID: EventTargetHit, CompetitorID: 1, Target: 2},
+		{Timestamp: testTime(10, 5, 6, 0), ID: EventTargetHit, CompetitorID: 1, Target: 3},
+		{Timestamp: testTime(10, 5, 8, 0), ID: EventTargetHit, CompetitorID: 1, Target: 4},
+		{Timestamp: testTime(10, 5, 15, 0), ID: EventSpareLoaded, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 17, 0), ID: EventTargetHit, CompetitorID: 1, Target: 5},
+		{Timestamp: testTime(10, 5, 25, 0), ID: EventLeftFiringRange, CompetitorID: 1},
+	}
+	sim.Run(events)
+
+	sr := sim.Competitors[1].LapsData[0].ShootingData[0]
+	if sr.Shots != 6 || sr.SparesUsed != 1 || sr.Hits != 5 || sr.PenaltiesIncurred != 0 {
+		t.Errorf("ShootingRecord mismatch: %+v", sr)
+	}
+}
+
+func TestComputeTeamStandings_TimePenalty(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mode = ModeRelay
+	sim := NewSimulation(cfg)
+	sim.ApplyRoster(Roster{
+		1: {"team": "NOR", "leg": "1"},
+		2: {"team": "NOR", "leg": "2"},
+		3: {"team": "FRA", "leg": "1"},
+		4: {"team": "FRA", "leg": "2"},
+	})
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 3},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventRelayHandOff, CompetitorID: 1, NextCompetitorID: 2},
+		{Timestamp: testTime(10, 10, 30, 0), ID: EventEndedMainLap, CompetitorID: 3},
+		{Timestamp: testTime(10, 10, 30, 0), ID: EventRelayHandOff, CompetitorID: 3, NextCompetitorID: 4},
+		{Timestamp: testTime(10, 19, 0, 0), ID: EventEndedMainLap, CompetitorID: 2},
+		{Timestamp: testTime(10, 19, 30, 0), ID: EventEndedMainLap, CompetitorID: 4},
+		{Timestamp: testTime(10, 25, 0, 0), ID: EventTimePenalty, CompetitorID: 1, Penalty: 2 * time.Minute, Comment: "Missed penalty loop"},
+	}
+	sim.Run(events)
+	sim.FinalizeResults()
+
+	standings := ComputeTeamStandings(sim.Competitors, cfg)
+	if len(standings) != 2 {
+		t.Fatalf("Expected 2 teams, got %d", len(standings))
+	}
+	fra, nor := standings[0], standings[1]
+	if fra.Team != "FRA" || fra.TotalTime != 19*time.Minute+30*time.Second {
+		t.Errorf("FRA standing mismatch: %+v", fra)
+	}
+	if nor.Team != "NOR" || nor.TotalTime != 21*time.Minute {
+		t.Errorf("NOR standing mismatch: %+v", nor)
+	}
+	if nor.Legs[0].Duration != 12*time.Minute || nor.Legs[1].Duration != 9*time.Minute {
+		t.Errorf("NOR leg splits mismatch: %+v", nor.Legs)
+	}
+}
+
+func TestGenerateTeamReport_SharedRanks(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Mode = ModeRelay
+	sim := NewSimulation(cfg)
+	sim.ApplyRoster(Roster{
+		1: {"team": "NOR", "leg": "1"},
+		2: {"team": "FRA", "leg": "1"},
+		3: {"team": "GER", "leg": "1"},
+		4: {"team": "SWE", "leg": "1"},
+	})
+
+	// NOR and FRA differ by less than the tenth of a second official times
+	// are truncated to.
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 3},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 4},
+		{Timestamp: testTime(10, 9, 0, 0), ID: EventEndedMainLap, CompetitorID: 3},
+		{Timestamp: testTime(10, 10, 0, 30), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 10, 0, 70), ID: EventEndedMainLap, CompetitorID: 2},
+		{Timestamp: testTime(10, 11, 0, 0), ID: EventEndedMainLap, CompetitorID: 4},
+	}
+	sim.Run(events)
+	sim.FinalizeResults()
+
+	var ranks []string
+	for _, standing := range ComputeTeamStandings(sim.Competitors, cfg) {
+		ranks = append(ranks, standing.Team+" "+standing.RankString())
+	}
+	if got, want := strings.Join(ranks, ", "), "GER 1, FRA =2, NOR =2, SWE 4"; got != want {
+		t.Errorf("Team ranks: got %s, want %s", got, want)
+	}
+
+	var buf bytes.Buffer
+	GenerateTeamReport(&buf, sim.Competitors, cfg)
+	if !strings.Contains(buf.String(), "=2    FRA ") || !strings.Contains(buf.String(), "=2    NOR ") {
+		t.Errorf("Team report should show the shared rank:\n%s", buf.String())
+	}
+}
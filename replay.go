@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ReplayCommandKind int
+
+const (
+	ReplayPause ReplayCommandKind = iota
+	ReplayResume
+	ReplaySeek
+	ReplaySpeed
+)
+
+type ReplayCommand struct {
+	Kind  ReplayCommandKind
+	Clock time.Time
+	Speed float64
+}
+
+// ParseReplayCommand parses one control line: "pause", "resume",
+// "seek HH:MM:SS[.mmm]" or "speed N".
+func ParseReplayCommand(line string) (ReplayCommand, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ReplayCommand{}, fmt.Errorf("empty replay command")
+	}
+	switch fields[0] {
+	case "pause":
+		return ReplayCommand{Kind: ReplayPause}, nil
+	case "resume":
+		return ReplayCommand{Kind: ReplayResume}, nil
+	case "seek":
+		if len(fields) != 2 {
+			return ReplayCommand{}, fmt.Errorf("seek expects a race clock time")
+		}
+		clock, err := ParseTime(fields[1])
+		if err != nil {
+			return ReplayCommand{}, err
+		}
+		return ReplayCommand{Kind: ReplaySeek, Clock: clock}, nil
+	case "speed":
+		if len(fields) != 2 {
+			return ReplayCommand{}, fmt.Errorf("speed expects a factor")
+		}
+		speed, err := strconv.ParseFloat(strings.TrimSuffix(fields[1], "x"), 64)
+		if err != nil || speed < 0 {
+			return ReplayCommand{}, fmt.Errorf("invalid speed factor '%s'", fields[1])
+		}
+		return ReplayCommand{Kind: ReplaySpeed, Speed: speed}, nil
+	default:
+		return ReplayCommand{}, fmt.Errorf("unknown replay command '%s'", fields[0])
+	}
+}
+
+// ReadReplayCommands turns control lines from r into commands until r is
+// exhausted, reporting unparsable lines to errOut.
+func ReadReplayCommands(r io.Reader, errOut io.Writer) <-chan ReplayCommand {
+	commands := make(chan ReplayCommand)
+	go func() {
+		defer close(commands)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			cmd, err := ParseReplayCommand(line)
+			if err != nil {
+				fmt.Fprintf(errOut, "Warning: %v\n", err)
+				continue
+			}
+			commands <- cmd
+		}
+	}()
+	return commands
+}
+
+// Replayer feeds a recorded events log into a simulation in real time, scaled
+// by Speed relative to the event timestamps. A Speed of zero replays without
+// waiting.
+type Replayer struct {
+	NewSimulation func() *Simulation
+	Output        *LiveOutput
+	Speed         float64
+	PauseAt       time.Time
+	Controls      <-chan ReplayCommand
+
+	Now   func() time.Time
+	After func(time.Duration) <-chan time.Time
+
+	Simulation *Simulation
+
+	events []Event
+	pos    int
+	clock  time.Time
+	paused bool
+}
+
+func NewReplayer(events []Event, newSimulation func() *Simulation, output *LiveOutput, speed float64) *Replayer {
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+	return &Replayer{
+		NewSimulation: newSimulation,
+		Output:        output,
+		Speed:         speed,
+		Now:           time.Now,
+		After:         time.After,
+		events:        sorted,
+	}
+}
+
+func (r *Replayer) reset() {
+	r.Simulation = r.NewSimulation()
+	r.pos = 0
+	r.clock = time.Time{}
+	if len(r.events) > 0 {
+		r.clock = r.events[0].Timestamp
+	}
+}
+
+// Clock returns the current race clock of the replay.
+func (r *Replayer) Clock() time.Time {
+	return r.clock
+}
+
+// Seek moves the race clock to the given time. Events up to it are processed
+// without output; seeking backwards restarts the simulation from scratch.
+func (r *Replayer) Seek(clock time.Time) error {
+	if clock.Before(r.clock) {
+		r.reset()
+	}
+	for r.pos < len(r.events) && !r.events[r.pos].Timestamp.After(clock) {
+		r.Simulation.Step(r.events[r.pos])
+		r.pos++
+	}
+	r.clock = clock
+	r.Output.Skip(r.Simulation)
+	return r.Output.Snapshot(r.Simulation, clock)
+}
+
+func (r *Replayer) handle(cmd ReplayCommand) error {
+	switch cmd.Kind {
+	case ReplayPause:
+		if !r.paused {
+			r.paused = true
+			return r.Output.Snapshot(r.Simulation, r.clock)
+		}
+	case ReplayResume:
+		r.paused = false
+	case ReplaySeek:
+		return r.Seek(cmd.Clock)
+	case ReplaySpeed:
+		r.Speed = cmd.Speed
+	}
+	return nil
+}
+
+// Run replays every remaining event and checks for competitors that never
+// started, leaving the simulation ready for FinalizeResults. If the replay is
+// paused when the controls are exhausted it stops at the current clock.
+func (r *Replayer) Run() error {
+	if r.Simulation == nil {
+		r.reset()
+	}
+	pauseAtPending := !r.PauseAt.IsZero()
+	controls := r.Controls
+
+	for r.pos < len(r.events) {
+		next := r.events[r.pos]
+
+		// A seek past the pause time drops the pause instead of moving the
+		// clock back to it.
+		if pauseAtPending && r.clock.After(r.PauseAt) {
+			pauseAtPending = false
+		}
+		if pauseAtPending && !next.Timestamp.Before(r.PauseAt) {
+			pauseAtPending = false
+			r.clock = r.PauseAt
+			if err := r.handle(ReplayCommand{Kind: ReplayPause}); err != nil {
+				return err
+			}
+		}
+
+		if r.paused {
+			if controls == nil {
+				break
+			}
+			cmd, ok := <-controls
+			if !ok {
+				break
+			}
+			if err := r.handle(cmd); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if wait := next.Timestamp.Sub(r.clock); wait > 0 && r.Speed > 0 {
+			scaled := time.Duration(float64(wait) / r.Speed)
+			started := r.Now()
+			select {
+			case <-r.After(scaled):
+			case cmd, ok := <-controls:
+				elapsed := time.Duration(float64(r.Now().Sub(started)) * r.Speed)
+				r.clock = r.clock.Add(min(elapsed, wait))
+				if !ok {
+					controls = nil
+					continue
+				}
+				if err := r.handle(cmd); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		r.Simulation.Step(next)
+		r.pos++
+		r.clock = next.Timestamp
+		if err := r.Output.Emit(r.Simulation, next); err != nil {
+			return err
+		}
+	}
+
+	r.Simulation.checkForNotStarted()
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func replayTestEvents() []Event {
+	return []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 30, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 11, 0, 0), ID: EventEndedMainLap, CompetitorID: 2},
+	}
+}
+
+func newTestReplayer(speed float64) (*Replayer, *[]time.Duration, *bytes.Buffer) {
+	cfg := createTestConfig()
+	var out bytes.Buffer
+	output := &LiveOutput{Log: &out}
+	r := NewReplayer(replayTestEvents(), func() *Simulation { return NewSimulation(cfg) }, output, speed)
+
+	var waits []time.Duration
+	r.After = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	return r, &waits, &out
+}
+
+func TestReplayer_ScalesWaitsBySpeed(t *testing.T) {
+	r, waits, out := newTestReplayer(10)
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []time.Duration{3 * time.Second, 57 * time.Second, 6 * time.Second}
+	if len(*waits) != len(want) {
+		t.Fatalf("Expected %d waits, got %d: %v", len(want), len(*waits), *waits)
+	}
+	for i, w := range want {
+		if (*waits)[i] != w {
+			t.Errorf("Wait %d: got %v, want %v", i, (*waits)[i], w)
+		}
+	}
+	if r.Simulation.Competitors[1].Status != StatusCompleted {
+		t.Errorf("Competitor 1 status: got %s, want %s", r.Simulation.Competitors[1].Status, StatusCompleted)
+	}
+	if got := strings.Count(out.String(), "\n"); got != 6 {
+		t.Errorf("Expected 6 log lines including finishes, got %d:\n%s", got, out.String())
+	}
+}
+
+func TestReplayer_PauseAtWithoutControlsStops(t *testing.T) {
+	r, _, out := newTestReplayer(0)
+	r.PauseAt = testTime(10, 5, 0, 0)
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !r.Clock().Equal(r.PauseAt) {
+		t.Errorf("Clock: got %v, want %v", r.Clock(), r.PauseAt)
+	}
+	if r.Simulation.Competitors[1].Status != StatusRacing {
+		t.Errorf("Competitor 1 status: got %s, want %s", r.Simulation.Competitors[1].Status, StatusRacing)
+	}
+	if !strings.Contains(out.String(), "Standings at 10:05:00.000") {
+		t.Errorf("Expected a standings snapshot at the pause, got:\n%s", out.String())
+	}
+}
+
+func TestReplayer_SeekPastPauseAt(t *testing.T) {
+	r, _, out := newTestReplayer(0)
+	r.PauseAt = testTime(10, 5, 0, 0)
+	r.reset()
+	if err := r.Seek(testTime(10, 10, 30, 0)); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if r.Clock().Before(testTime(10, 11, 0, 0)) {
+		t.Errorf("Clock: got %v, want the last event at 10:11:00", r.Clock())
+	}
+	if r.Simulation.Competitors[2].Status != StatusCompleted {
+		t.Errorf("Competitor 2 status: got %s, want %s", r.Simulation.Competitors[2].Status, StatusCompleted)
+	}
+	if strings.Contains(out.String(), "Standings at 10:05:00.000") {
+		t.Errorf("A pause time behind the seek should be dropped, got:\n%s", out.String())
+	}
+}
+
+func TestReplayer_SeekAndControls(t *testing.T) {
+	r, _, out := newTestReplayer(0)
+	controls := make(chan ReplayCommand, 3)
+	controls <- ReplayCommand{Kind: ReplaySeek, Clock: testTime(10, 5, 0, 0)}
+	controls <- ReplayCommand{Kind: ReplayResume}
+	close(controls)
+	r.Controls = controls
+
+	r.reset()
+	if err := r.Seek(testTime(10, 10, 30, 0)); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if r.Simulation.Competitors[1].Status != StatusCompleted {
+		t.Fatalf("Competitor 1 should have finished after seeking forward")
+	}
+
+	r.paused = true
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := len(r.Simulation.OutputLog); got != 6 {
+		t.Errorf("Seeking backwards should rebuild the simulation, got %d log entries", got)
+	}
+	if strings.Contains(out.String(), "has started") {
+		t.Errorf("Events skipped by seeking should not be emitted:\n%s", out.String())
+	}
+}
+
+func TestParseReplayCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    ReplayCommandKind
+		wantErr bool
+	}{
+		{"pause", ReplayPause, false},
+		{"resume", ReplayResume, false},
+		{"seek 10:05:00", ReplaySeek, false},
+		{"speed 10x", ReplaySpeed, false},
+		{"seek", 0, true},
+		{"rewind", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := ParseReplayCommand(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReplayCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Kind != tt.want {
+				t.Errorf("ParseReplayCommand() kind = %v, want %v", got.Kind, tt.want)
+			}
+		})
+	}
+}
@@ -2,87 +2,76 @@ package main
 
 import (
 	"fmt"
-	"sort"
+	"io"
 )
 
-func GenerateOutputLog(logEntries []string) {
-	fmt.Println("Output log")
-	fmt.Println("----------")
-	for _, entry := range logEntries {
-		fmt.Println(entry)
-	}
-	fmt.Println()
+func GenerateOutputLog(w io.Writer, logEntries []LogEntry) {
+	fmt.Fprintln(w, "Output log")
+	fmt.Fprintln(w, "----------")
+	WriteLogText(w, logEntries)
+	fmt.Fprintln(w)
 }
 
-func GenerateFinalReport(competitors map[int]*Competitor, config *Config) {
-	fmt.Println("Resulting table")
-	fmt.Println("---------------")
-
-	var sortedCompetitors []*Competitor
-	for _, c := range competitors {
-		sortedCompetitors = append(sortedCompetitors, c)
+func formatPenaltyStats(c *Competitor, config *Config) string {
+	penaltyStats := c.CalculatePenaltyStats(config)
+	timing := config.Timing()
+	penaltyStr := fmt.Sprintf("{%s, %.3f}", timing.Format(penaltyStats.TotalTime), penaltyStats.AverageSpeed)
+	if penaltyStats.TotalLaps == 0 {
+		if penaltyStats.TotalTime == 0 {
+			penaltyStr = fmt.Sprintf("{%s, 0.000}", timing.Format(0))
+		}
 	}
+	return penaltyStr
+}
 
-	sort.Slice(sortedCompetitors, func(i, j int) bool {
-		c1 := sortedCompetitors[i]
-		c2 := sortedCompetitors[j]
-
-		c1Finished := c1.Status == StatusCompleted && !c1.FinishTime.IsZero()
-		c2Finished := c2.Status == StatusCompleted && !c2.FinishTime.IsZero()
-
-		if c1Finished && c2Finished {
-			t1 := c1.FinishTime.Sub(c1.ActualStartTime)
-			t2 := c2.FinishTime.Sub(c2.ActualStartTime)
-			if t1 != t2 {
-				return t1 < t2
-			}
-			return c1.ID < c2.ID
-		}
-		if c1Finished {
-			return true
-		}
-		if c2Finished {
-			return false
-		}
+func GenerateFinalReport(w io.Writer, competitors map[int]*Competitor, config *Config) {
+	fmt.Fprintln(w, "Resulting table")
+	fmt.Fprintln(w, "---------------")
 
-		statusOrder := func(s CompetitorStatus) int {
-			switch s {
-			case StatusNotFinished:
-				return 1
-			case StatusNotStarted:
-				return 2
-			case StatusDisqualified:
-				return 3
-			default:
-				return 4
-			}
-		}
-		if statusOrder(c1.Status) != statusOrder(c2.Status) {
-			return statusOrder(c1.Status) < statusOrder(c2.Status)
-		}
-		return c1.ID < c2.ID
-	})
+	ranking := RankCompetitors(competitors, config)
 
-	headerFormat := "%-15s %-5s %-45s %-23s %-10s\n"
-	fmt.Printf(headerFormat, "Result/Status", "ID", "Lap Details (Time, Speed m/s)", "Penalty (Time, Speed m/s)", "Shooting")
+	headerFormat := "%-5s %-15s %-14s %-5s %-45s %-23s %-10s\n"
+	fmt.Fprintf(w, headerFormat, "Rank", "Result/Status", "Behind", "ID", "Lap Details (Time, Speed m/s)", "Penalty (Time, Speed m/s)", "Shooting")
 
-	for _, c := range sortedCompetitors {
-		statusStr := c.GetOverallStatusForReport()
+	for _, entry := range ranking {
+		c := entry.Competitor
+		statusStr := c.GetOverallStatusForReport(config)
 		lapResultsStr := c.FormatLapResults(config)
-		penaltyStats := c.CalculatePenaltyStats(config)
-		penaltyStr := fmt.Sprintf("{%s, %.3f}", FormatDuration(penaltyStats.TotalTime), penaltyStats.AverageSpeed)
-		if penaltyStats.TotalLaps == 0 {
-			if penaltyStats.TotalTime == 0 {
-				penaltyStr = fmt.Sprintf("{%s, 0.000}", FormatDuration(0))
-			}
-		}
+		penaltyStr := formatPenaltyStats(c, config)
 		shootingStr := c.FinalShootingString()
 
-		fmt.Printf("%-15s %-5d %-45s %-23s %-10s\n",
+		fmt.Fprintf(w, "%-5s %-15s %-14s %-5d %-45s %-23s %-10s\n",
+			entry.RankString(),
 			statusStr,
+			entry.BehindString(),
 			c.ID,
 			lapResultsStr,
 			penaltyStr,
 			shootingStr)
 	}
+
+	if len(config.GroupBy) == 0 {
+		return
+	}
+
+	for _, group := range GroupResults(ranking, config.GroupBy, config) {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, group.Key)
+		fmt.Fprintln(w, "---------------")
+
+		groupHeaderFormat := "%-5s %-7s %-15s %-14s %-5s %-45s %-23s %-10s\n"
+		fmt.Fprintf(w, groupHeaderFormat, "Rank", "Overall", "Result/Status", "Behind", "ID", "Lap Details (Time, Speed m/s)", "Penalty (Time, Speed m/s)", "Shooting")
+		for _, entry := range group.Entries {
+			c := entry.Competitor
+			fmt.Fprintf(w, groupHeaderFormat,
+				entry.Group.RankString(),
+				formatRank(entry.Overall.Rank, entry.Overall.Tied),
+				c.GetOverallStatusForReport(config),
+				entry.Group.BehindString(),
+				fmt.Sprint(c.ID),
+				c.FormatLapResults(config),
+				formatPenaltyStats(c, config),
+				c.FinalShootingString())
+		}
+	}
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+type Roster map[int]map[string]string
+
+func LoadRoster(filePath string) (Roster, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roster file '%s': %w", filePath, err)
+	}
+
+	var raw map[string]map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal roster JSON from '%s': %w", filePath, err)
+	}
+
+	roster := make(Roster, len(raw))
+	for idStr, attributes := range raw {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid competitor ID '%s' in roster '%s': %w", idStr, filePath, err)
+		}
+		roster[id] = attributes
+	}
+	return roster, nil
+}
+
+// ApplyRoster attaches the roster attributes to the competitors of the race.
+// A competitor gets them when their first event arrives, so roster entries
+// without events add no competitors.
+func (s *Simulation) ApplyRoster(roster Roster) {
+	s.Roster = roster
+	for _, competitor := range s.Competitors {
+		s.attachAttributes(competitor)
+	}
+}
+
+func (s *Simulation) attachAttributes(competitor *Competitor) {
+	attributes, ok := s.Roster[competitor.ID]
+	if !ok {
+		return
+	}
+	if competitor.Attributes == nil {
+		competitor.Attributes = make(map[string]string, len(attributes))
+	}
+	for key, value := range attributes {
+		competitor.Attributes[key] = value
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IBUPointsTable awards World Cup points to ranks 1 to 40.
+var IBUPointsTable = []int{
+	90, 75, 60, 50, 45, 40, 36, 34, 32, 31,
+	30, 29, 28, 27, 26, 25, 24, 23, 22, 21,
+	20, 19, 18, 17, 16, 15, 14, 13, 12, 11,
+	10, 9, 8, 7, 6, 5, 4, 3, 2, 1,
+}
+
+type PointsTable []int
+
+func LoadPointsTable(filePath string) (PointsTable, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read points table '%s': %w", filePath, err)
+	}
+	var table PointsTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal points table JSON from '%s': %w", filePath, err)
+	}
+	return table, nil
+}
+
+func (p PointsTable) Points(rank int) int {
+	if rank <= 0 || rank > len(p) {
+		return 0
+	}
+	return p[rank-1]
+}
+
+type SeasonResult struct {
+	RaceID     string `json:"raceId"`
+	Date       string `json:"date"`
+	Discipline string `json:"discipline,omitempty"`
+	Rank       int    `json:"rank,omitempty"`
+	Status     string `json:"status"`
+	Points     int    `json:"points"`
+	Dropped    bool   `json:"dropped,omitempty"`
+}
+
+type SeasonStanding struct {
+	Rank    int            `json:"rank"`
+	Athlete string         `json:"athlete"`
+	Points  int            `json:"points"`
+	Results []SeasonResult `json:"results"`
+
+	placings []int
+}
+
+type SeasonStandings struct {
+	Discipline string           `json:"discipline,omitempty"`
+	Races      []string         `json:"races"`
+	Standings  []SeasonStanding `json:"standings"`
+}
+
+// ComputeSeasonStandings awards points for every race and totals them per
+// athlete. The dropWorst lowest scores of each athlete are discarded, counting
+// races they did not take part in as zero. Equal totals are split by the number
+// of better placings (wins, then second places, and so on); athletes that are
+// still level share the rank.
+func ComputeSeasonStandings(races []RaceHistory, table PointsTable, dropWorst int) SeasonStandings {
+	season := SeasonStandings{}
+	byAthlete := make(map[string]*SeasonStanding)
+
+	for _, race := range races {
+		season.Races = append(season.Races, race.RaceID)
+		for _, record := range race.Competitors {
+			standing, ok := byAthlete[record.Athlete]
+			if !ok {
+				standing = &SeasonStanding{Athlete: record.Athlete, placings: make([]int, len(table))}
+				byAthlete[record.Athlete] = standing
+			}
+			result := SeasonResult{
+				RaceID:     race.RaceID,
+				Date:       race.Date,
+				Discipline: race.Discipline,
+				Rank:       record.Rank,
+				Status:     string(record.Status),
+				Points:     table.Points(record.Rank),
+			}
+			standing.Results = append(standing.Results, result)
+			if record.Rank > 0 && record.Rank <= len(table) {
+				standing.placings[record.Rank-1]++
+			}
+		}
+	}
+
+	for _, standing := range byAthlete {
+		dropResults(standing, len(races), dropWorst)
+		for _, result := range standing.Results {
+			if !result.Dropped {
+				standing.Points += result.Points
+			}
+		}
+		season.Standings = append(season.Standings, *standing)
+	}
+
+	sort.Slice(season.Standings, func(i, j int) bool {
+		s1, s2 := season.Standings[i], season.Standings[j]
+		if s1.Points != s2.Points {
+			return s1.Points > s2.Points
+		}
+		if cmp := comparePlacings(s1.placings, s2.placings); cmp != 0 {
+			return cmp > 0
+		}
+		return s1.Athlete < s2.Athlete
+	})
+	for i := range season.Standings {
+		season.Standings[i].Rank = i + 1
+		if i > 0 {
+			prev := season.Standings[i-1]
+			if prev.Points == season.Standings[i].Points && comparePlacings(prev.placings, season.Standings[i].placings) == 0 {
+				season.Standings[i].Rank = prev.Rank
+			}
+		}
+	}
+	return season
+}
+
+// dropResults marks the athlete's lowest scores as dropped. Races the athlete
+// missed are worth zero and are dropped first. At least one race always counts.
+func dropResults(standing *SeasonStanding, races, dropWorst int) {
+	dropWorst = min(dropWorst, races-1)
+	missed := races - len(standing.Results)
+	toDrop := dropWorst - missed
+	if toDrop <= 0 {
+		return
+	}
+
+	order := make([]int, len(standing.Results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return standing.Results[order[a]].Points < standing.Results[order[b]].Points
+	})
+	for _, idx := range order[:min(toDrop, len(order))] {
+		standing.Results[idx].Dropped = true
+	}
+}
+
+func comparePlacings(p1, p2 []int) int {
+	for i := range p1 {
+		if p1[i] != p2[i] {
+			if p1[i] > p2[i] {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// ComputeDisciplineStandings computes separate standings for every discipline
+// found in the races, ordered by discipline name. When no race has a
+// discipline there is nothing to add to the overall standings.
+func ComputeDisciplineStandings(races []RaceHistory, table PointsTable, dropWorst int) []SeasonStandings {
+	byDiscipline := make(map[string][]RaceHistory)
+	for _, race := range races {
+		byDiscipline[race.Discipline] = append(byDiscipline[race.Discipline], race)
+	}
+	if _, ok := byDiscipline[""]; ok && len(byDiscipline) == 1 {
+		return nil
+	}
+
+	disciplines := make([]string, 0, len(byDiscipline))
+	for discipline := range byDiscipline {
+		disciplines = append(disciplines, discipline)
+	}
+	sort.Strings(disciplines)
+
+	result := make([]SeasonStandings, 0, len(disciplines))
+	for _, discipline := range disciplines {
+		standings := ComputeSeasonStandings(byDiscipline[discipline], table, dropWorst)
+		standings.Discipline = discipline
+		if discipline == "" {
+			standings.Discipline = "unspecified"
+		}
+		result = append(result, standings)
+	}
+	return result
+}
+
+func seasonTitle(s SeasonStandings) string {
+	if s.Discipline == "" {
+		return "Overall standings"
+	}
+	return fmt.Sprintf("%s standings", strings.ToUpper(s.Discipline[:1])+s.Discipline[1:])
+}
+
+func WriteSeasonText(w io.Writer, all []SeasonStandings) error {
+	for i, season := range all {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		title := seasonTitle(season)
+		fmt.Fprintln(w, title)
+		fmt.Fprintln(w, strings.Repeat("-", len(title)))
+
+		header := []string{"Rank", "Athlete", "Points"}
+		header = append(header, season.Races...)
+		fmt.Fprintln(w, strings.Join(padColumns(header), " "))
+		for _, standing := range season.Standings {
+			row := []string{strconv.Itoa(standing.Rank), standing.Athlete, strconv.Itoa(standing.Points)}
+			row = append(row, raceCells(season.Races, standing.Results)...)
+			if _, err := fmt.Fprintln(w, strings.Join(padColumns(row), " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func padColumns(cells []string) []string {
+	widths := []int{5, 15, 7}
+	padded := make([]string, len(cells))
+	for i, cell := range cells {
+		width := 10
+		if i < len(widths) {
+			width = widths[i]
+		}
+		padded[i] = fmt.Sprintf("%-*s", width, cell)
+	}
+	return padded
+}
+
+func raceCells(races []string, results []SeasonResult) []string {
+	byRace := make(map[string]SeasonResult, len(results))
+	for _, result := range results {
+		byRace[result.RaceID] = result
+	}
+	cells := make([]string, len(races))
+	for i, race := range races {
+		result, ok := byRace[race]
+		switch {
+		case !ok:
+			cells[i] = "-"
+		case result.Dropped:
+			cells[i] = fmt.Sprintf("(%d)", result.Points)
+		default:
+			cells[i] = strconv.Itoa(result.Points)
+		}
+	}
+	return cells
+}
+
+func WriteSeasonJSON(w io.Writer, all []SeasonStandings) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(all)
+}
+
+func WriteSeasonCSV(w io.Writer, all []SeasonStandings) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Discipline", "Rank", "Athlete", "Points", "Race", "RaceRank", "RacePoints", "Dropped"}); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, season := range all {
+		discipline := season.Discipline
+		if discipline == "" {
+			discipline = "overall"
+		}
+		for _, standing := range season.Standings {
+			for _, result := range standing.Results {
+				record := []string{
+					discipline,
+					strconv.Itoa(standing.Rank),
+					standing.Athlete,
+					strconv.Itoa(standing.Points),
+					result.RaceID,
+					formatRank(result.Rank, false),
+					strconv.Itoa(result.Points),
+					strconv.FormatBool(result.Dropped),
+				}
+				if err := writer.Write(record); err != nil {
+					return fmt.Errorf("failed to write CSV record for athlete '%s': %w", standing.Athlete, err)
+				}
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func seasonTestRace(id, discipline string, ranking ...string) RaceHistory {
+	race := RaceHistory{RaceID: id, Date: "2026-01-01", Discipline: discipline}
+	for i, athlete := range ranking {
+		record := CompetitorHistory{Athlete: athlete, Status: StatusCompleted, Rank: i + 1}
+		if strings.HasSuffix(athlete, "*") {
+			record = CompetitorHistory{Athlete: strings.TrimSuffix(athlete, "*"), Status: StatusNotFinished}
+		}
+		race.Competitors = append(race.Competitors, record)
+	}
+	return race
+}
+
+func TestComputeSeasonStandings(t *testing.T) {
+	races := []RaceHistory{
+		seasonTestRace("r1", "sprint", "A", "B", "C"),
+		seasonTestRace("r2", "pursuit", "B", "A", "C*"),
+		seasonTestRace("r3", "sprint", "C", "D"),
+	}
+
+	tests := []struct {
+		name       string
+		dropWorst  int
+		wantOrder  []string
+		wantPoints []int
+		wantRanks  []int
+	}{
+		{"NoDrops", 0, []string{"A", "B", "C", "D"}, []int{165, 165, 150, 75}, []int{1, 1, 3, 4}},
+		{"DropWorstOne", 1, []string{"A", "B", "C", "D"}, []int{165, 165, 150, 75}, []int{1, 1, 3, 4}},
+		{"DropWorstTwo", 2, []string{"A", "B", "C", "D"}, []int{90, 90, 90, 75}, []int{1, 1, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			season := ComputeSeasonStandings(races, IBUPointsTable, tt.dropWorst)
+			if len(season.Standings) != len(tt.wantOrder) {
+				t.Fatalf("Expected %d athletes, got %d", len(tt.wantOrder), len(season.Standings))
+			}
+			for i, standing := range season.Standings {
+				if standing.Athlete != tt.wantOrder[i] || standing.Points != tt.wantPoints[i] || standing.Rank != tt.wantRanks[i] {
+					t.Errorf("Standing %d: got %s %d pts rank %d, want %s %d pts rank %d",
+						i, standing.Athlete, standing.Points, standing.Rank, tt.wantOrder[i], tt.wantPoints[i], tt.wantRanks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestComputeSeasonStandings_TiebreakByPlacings(t *testing.T) {
+	races := []RaceHistory{
+		seasonTestRace("r1", "sprint", "A", "B", "X", "Y"),
+		seasonTestRace("r2", "sprint", "Y", "B", "X", "A"),
+	}
+	table := PointsTable{10, 6, 4, 2}
+	season := ComputeSeasonStandings(races, table, 0)
+
+	// A, B and Y all score 12. A and Y each have a win and a fourth place and
+	// share the lead, B with two second places is third.
+	wantOrder := []string{"A", "Y", "B", "X"}
+	wantRanks := []int{1, 1, 3, 4}
+	for i, standing := range season.Standings {
+		if standing.Athlete != wantOrder[i] || standing.Rank != wantRanks[i] {
+			t.Errorf("Standing %d: got %s rank %d, want %s rank %d", i, standing.Athlete, standing.Rank, wantOrder[i], wantRanks[i])
+		}
+	}
+}
+
+func TestSeasonOutputs(t *testing.T) {
+	races := []RaceHistory{
+		seasonTestRace("r1", "sprint", "A", "B"),
+		seasonTestRace("r2", "pursuit", "B", "A"),
+	}
+	all := []SeasonStandings{ComputeSeasonStandings(races, IBUPointsTable, 0)}
+	all = append(all, ComputeDisciplineStandings(races, IBUPointsTable, 0)...)
+	if len(all) != 3 || all[1].Discipline != "pursuit" || all[2].Discipline != "sprint" {
+		t.Fatalf("Unexpected discipline standings: %+v", all)
+	}
+
+	unspecified := []RaceHistory{seasonTestRace("r1", "", "A", "B"), seasonTestRace("r2", "", "B", "A")}
+	if got := ComputeDisciplineStandings(unspecified, IBUPointsTable, 0); len(got) != 0 {
+		t.Errorf("Races without a discipline: got %d discipline tables, want none", len(got))
+	}
+
+	var text, js, csv bytes.Buffer
+	if err := WriteSeasonText(&text, all); err != nil {
+		t.Fatalf("WriteSeasonText() error = %v", err)
+	}
+	if !strings.Contains(text.String(), "Pursuit standings") {
+		t.Errorf("Text output missing discipline table:\n%s", text.String())
+	}
+
+	if err := WriteSeasonJSON(&js, all); err != nil {
+		t.Fatalf("WriteSeasonJSON() error = %v", err)
+	}
+	var decoded []SeasonStandings
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON output does not parse: %v", err)
+	}
+	if decoded[0].Standings[0].Points != 165 {
+		t.Errorf("JSON overall leader points: got %d, want 165", decoded[0].Standings[0].Points)
+	}
+
+	if err := WriteSeasonCSV(&csv, all); err != nil {
+		t.Fatalf("WriteSeasonCSV() error = %v", err)
+	}
+	if lines := strings.Count(csv.String(), "\n"); lines != 1+4+2+2 {
+		t.Errorf("CSV output: got %d lines\n%s", lines, csv.String())
+	}
+}
@@ -1,21 +1,29 @@
 package main
 
 import (
-	"fmt"
+	"slices"
 	"sort"
+	"time"
+)
+
+const (
+	TargetsPerStage  = 5
+	RelaySpareRounds = 3
 )
 
 type Simulation struct {
 	Config      *Config
 	Competitors map[int]*Competitor
-	OutputLog   []string
+	OutputLog   []LogEntry
+	Position    EventPosition
+	Roster      Roster
 }
 
 func NewSimulation(config *Config) *Simulation {
 	return &Simulation{
 		Config:      config,
 		Competitors: make(map[int]*Competitor),
-		OutputLog:   make([]string, 0),
+		OutputLog:   make([]LogEntry, 0),
 	}
 }
 
@@ -31,57 +39,121 @@ func (s *Simulation) Run(incomingEvents []Event) {
 	s.checkForNotStarted()
 }
 
+// Step processes a single event in arrival order, for callers that feed the
+// race incrementally instead of handing the whole log to Run.
+func (s *Simulation) Step(event Event) {
+	s.processEvent(event)
+}
+
 func (s *Simulation) processEvent(event Event) {
-	s.OutputLog = append(s.OutputLog, fmt.Sprintf("[%s] %s", FormatTime(event.Timestamp), GetEventDescription(event)))
+	s.Position = EventPosition{
+		Index:        s.Position.Index + 1,
+		Time:         event.Timestamp,
+		ID:           event.ID,
+		CompetitorID: event.CompetitorID,
+		Source:       event.Source,
+	}
+	s.logEvent(event, false)
+	if event.ID == EventSyncPulse {
+		return
+	}
 
-	competitor := GetOrCreateCompetitor(event.CompetitorID, s.Competitors)
+	competitor := s.competitor(event.CompetitorID)
 	competitor.LastEventTime = event.Timestamp
 
+	// A competitor pulled as lapped has left the course, so only jury
+	// decisions still apply to them.
+	if competitor.Status == StatusLapped {
+		switch event.ID {
+		case EventEndedMainLap, EventJuryDisqualified, EventTimePenalty:
+		default:
+			s.warn(event, competitor.ID, "Competitor %d received event %d after being pulled as lapped.", competitor.ID, event.ID)
+			return
+		}
+	}
+
 	switch event.ID {
 	case EventRegistered:
-		competitor.Status = StatusRegistered
+		setCourseStatus(competitor, StatusRegistered)
 	case EventStartTimeSet:
 		competitor.ScheduledStartTime = event.ScheduledStartTime
-		competitor.Status = StatusScheduled
+		setCourseStatus(competitor, StatusScheduled)
 	case EventOnStartLine:
 	case EventStarted:
 		if competitor.Status == StatusNotStarted || competitor.Status == StatusDisqualified {
-			s.OutputLog = append(s.OutputLog, fmt.Sprintf("Warning: Competitor %d received Start event but is already %s.", competitor.ID, competitor.Status))
+			s.warn(event, competitor.ID, "Competitor %d received Start event but is already %s.", competitor.ID, competitor.Status)
 			return
 		}
-		competitor.ActualStartTime = event.Timestamp
-		competitor.Status = StatusRacing
-		competitor.CurrentLapNumber = 1
-		competitor.CurrentLapTempData.LapStartTime = event.Timestamp
-		if len(competitor.LapsData) == 0 {
-			lapData := LapRecord{
-				LapNumber:    competitor.CurrentLapNumber,
-				StartTime:    event.Timestamp,
-				ShootingData: make([]ShootingRecord, 0),
-			}
-			competitor.LapsData = append(competitor.LapsData, lapData)
+		if !competitor.ActualStartTime.IsZero() {
+			s.warn(event, competitor.ID, "Competitor %d restarted, the race since the start at %s is discarded.", competitor.ID, FormatTime(competitor.ActualStartTime))
+			resetRace(competitor)
 		}
+		startCompetitor(competitor, event.Timestamp)
 
 	case EventOnFiringRange:
-		competitor.Status = StatusOnRange
+		setCourseStatus(competitor, StatusOnRange)
 		competitor.CurrentLapTempData.RangeEntryTime = event.Timestamp
+		competitor.CurrentShooting = &ShootingRecord{
+			RangeID:   event.FiringRange,
+			EntryTime: event.Timestamp,
+		}
 		competitor.CurrentLapTempData.ShotsInSession = 0
+		competitor.CurrentLapTempData.SparesInSession = 0
 		competitor.CurrentLapTempData.HitsInSession = 0
+		competitor.CurrentLapTempData.TargetsHit = nil
+		competitor.CurrentLapTempData.ShotsCounted = 0
 	case EventTargetHit:
-		if competitor.Status != StatusOnRange {
-			s.OutputLog = append(s.OutputLog, fmt.Sprintf("Warning: Competitor %d (%s) received TargetHit event but is not on firing range.", competitor.ID, competitor.Status))
+		if !onFiringRange(competitor) {
+			s.warn(event, competitor.ID, "Competitor %d (%s) received TargetHit event but is not on firing range.", competitor.ID, competitor.Status)
+			return
+		}
+		s.recordTargetHit(competitor, event)
+		s.countSessionShots(competitor, max(competitor.CurrentLapTempData.ShotsInSession, competitor.CurrentLapTempData.HitsInSession))
+	case EventShotFired:
+		if !onFiringRange(competitor) {
+			s.warn(event, competitor.ID, "Competitor %d (%s) received ShotFired event but is not on firing range.", competitor.ID, competitor.Status)
+			return
+		}
+		// Once shots with an outcome are recorded they are the stage's hits,
+		// so hits counted from target hit events are dropped.
+		if event.Shot != ShotUnknown && !hasShotOutcomes(competitor.CurrentShooting) {
+			competitor.TotalHits -= competitor.CurrentLapTempData.HitsInSession
+			competitor.CurrentLapTempData.HitsInSession = 0
+		}
+		competitor.CurrentLapTempData.ShotsInSession++
+		competitor.CurrentShooting.ShotSequence = append(competitor.CurrentShooting.ShotSequence, ShotRecord{
+			Time:    event.Timestamp,
+			Target:  event.Target,
+			Outcome: event.Shot,
+			Spare:   competitor.CurrentLapTempData.ShotsInSession > TargetsPerStage,
+		})
+		if event.Shot == ShotHit {
+			competitor.CurrentLapTempData.HitsInSession++
+			competitor.TotalHits++
+		}
+		s.countSessionShots(competitor, max(competitor.CurrentLapTempData.ShotsInSession, competitor.CurrentLapTempData.HitsInSession))
+		if competitor.CurrentLapTempData.ShotsInSession > TargetsPerStage+competitor.CurrentLapTempData.SparesInSession {
+			s.warn(event, competitor.ID, "Competitor %d fired %d shots with only %d spare rounds loaded.", competitor.ID, competitor.CurrentLapTempData.ShotsInSession, competitor.CurrentLapTempData.SparesInSession)
+		}
+	case EventSpareLoaded:
+		if !onFiringRange(competitor) {
+			s.warn(event, competitor.ID, "Competitor %d (%s) received SpareLoaded event but is not on firing range.", competitor.ID, competitor.Status)
+		}
+		if !s.Config.IsRelay() {
+			s.warn(event, competitor.ID, "Competitor %d loaded a spare round but the race is not a relay.", competitor.ID)
+		}
+		competitor.CurrentLapTempData.SparesInSession++
+		if competitor.CurrentLapTempData.SparesInSession > RelaySpareRounds {
+			s.warn(event, competitor.ID, "Competitor %d loaded %d spare rounds, only %d are allowed.", competitor.ID, competitor.CurrentLapTempData.SparesInSession, RelaySpareRounds)
 		}
-		competitor.CurrentLapTempData.HitsInSession++
-		competitor.TotalHits++
 	case EventLeftFiringRange:
-		if competitor.Status != StatusOnRange {
-			s.OutputLog = append(s.OutputLog, fmt.Sprintf("Warning: Competitor %d (%s) received LeftFiringRange event but was not on firing range.", competitor.ID, competitor.Status))
+		if !onFiringRange(competitor) {
+			s.warn(event, competitor.ID, "Competitor %d (%s) received LeftFiringRange event but was not on firing range.", competitor.ID, competitor.Status)
 		}
 
-		shotsThisSession := 5
-		competitor.TotalShots += shotsThisSession
-
-		penalties := shotsThisSession - competitor.CurrentLapTempData.HitsInSession
+		shotsThisSession, sparesUsed, penalties := s.shootingOutcome(competitor)
+		s.countSessionShots(competitor, shotsThisSession)
+		competitor.CurrentLapTempData.ShotsCounted = 0
 		competitor.CurrentLapTempData.PenaltiesToServe = penalties
 
 		currentLapIdx := competitor.CurrentLapNumber - 1
@@ -92,22 +164,28 @@ func (s *Simulation) processEvent(event Event) {
 				ExitTime:          event.Timestamp,
 				Hits:              competitor.CurrentLapTempData.HitsInSession,
 				Shots:             shotsThisSession,
+				SparesUsed:        sparesUsed,
 				PenaltiesIncurred: penalties,
 			}
+			if competitor.CurrentShooting != nil {
+				sr.RangeID = competitor.CurrentShooting.RangeID
+				sr.ShotSequence = competitor.CurrentShooting.ShotSequence
+			}
 			competitor.LapsData[currentLapIdx].ShootingData = append(competitor.LapsData[currentLapIdx].ShootingData, sr)
 		}
 
+		competitor.CurrentShooting = nil
 		if penalties > 0 {
-			competitor.Status = StatusRacing
+			setCourseStatus(competitor, StatusRacing)
 		} else {
-			competitor.Status = StatusRacing
+			setCourseStatus(competitor, StatusRacing)
 		}
 
 	case EventEnteredPenaltyLaps:
-		competitor.Status = StatusInPenalty
+		setCourseStatus(competitor, StatusInPenalty)
 		competitor.CurrentLapTempData.PenaltyEntryTime = event.Timestamp
 	case EventLeftPenaltyLaps:
-		competitor.Status = StatusRacing
+		setCourseStatus(competitor, StatusRacing)
 
 		lapIdx := competitor.CurrentLapNumber - 1
 		if lapIdx >= 0 && lapIdx < len(competitor.LapsData) {
@@ -119,24 +197,30 @@ func (s *Simulation) processEvent(event Event) {
 		competitor.CurrentLapTempData.PenaltiesToServe = 0
 
 	case EventEndedMainLap:
+		if competitor.Status == StatusLapped {
+			s.warn(event, competitor.ID, "Competitor %d ended a lap after being pulled as lapped.", competitor.ID)
+			return
+		}
 		lapIdx := competitor.CurrentLapNumber - 1
 		if lapIdx >= 0 && lapIdx < len(competitor.LapsData) {
 			competitor.LapsData[lapIdx].EndTime = event.Timestamp
 		}
 
 		if competitor.CurrentLapNumber == s.Config.Laps {
-			competitor.Status = StatusCompleted
 			competitor.FinishTime = event.Timestamp
-			finishEvent := Event{
-				Timestamp:    event.Timestamp,
-				ID:           EventFinished,
-				CompetitorID: competitor.ID,
+			if competitor.Status != StatusDisqualified {
+				competitor.Status = StatusCompleted
+				finishEvent := Event{
+					Timestamp:    event.Timestamp,
+					ID:           EventFinished,
+					CompetitorID: competitor.ID,
+				}
+				competitor.GeneratedEvents = append(competitor.GeneratedEvents, finishEvent)
+				s.logEvent(finishEvent, true)
 			}
-			competitor.GeneratedEvents = append(competitor.GeneratedEvents, finishEvent)
-			s.OutputLog = append(s.OutputLog, fmt.Sprintf("[%s] %s", FormatTime(finishEvent.Timestamp), GetEventDescription(finishEvent)))
 		} else {
 			competitor.CurrentLapNumber++
-			competitor.Status = StatusRacing
+			setCourseStatus(competitor, StatusRacing)
 			competitor.CurrentLapTempData.LapStartTime = event.Timestamp
 			if len(competitor.LapsData) < competitor.CurrentLapNumber {
 				newLapData := LapRecord{
@@ -150,10 +234,188 @@ func (s *Simulation) processEvent(event Event) {
 			}
 		}
 
+		if s.Config.PullsLapped() {
+			s.pullLapped(event.Timestamp)
+		}
+
 	case EventCannotContinue:
-		competitor.Status = StatusNotFinished
+		setCourseStatus(competitor, StatusNotFinished)
 		competitor.DNFComment = event.Comment
+
+	case EventRelayHandOff:
+		if !s.Config.IsRelay() {
+			s.warn(event, competitor.ID, "Competitor %d handed off to competitor %d but the race is not a relay.", competitor.ID, event.NextCompetitorID)
+		}
+		if competitor.Status != StatusCompleted {
+			s.warn(event, competitor.ID, "Competitor %d (%s) handed off before completing the leg.", competitor.ID, competitor.Status)
+		}
+		next := s.competitor(event.NextCompetitorID)
+		next.LastEventTime = event.Timestamp
+		if next.Status == StatusNotStarted || next.Status == StatusDisqualified || !next.ActualStartTime.IsZero() {
+			s.warn(event, next.ID, "Competitor %d received hand-off but is already %s.", next.ID, next.Status)
+			return
+		}
+		startCompetitor(next, event.Timestamp)
+
+	case EventJuryDisqualified:
+		if competitor.Status == StatusDisqualified {
+			s.warn(event, competitor.ID, "Competitor %d is already disqualified.", competitor.ID)
+			return
+		}
+		competitor.Status = StatusDisqualified
+		competitor.DisqualificationReason = event.Comment
+		dsqEvent := Event{
+			Timestamp:    event.Timestamp,
+			ID:           EventDisqualified,
+			CompetitorID: competitor.ID,
+			Comment:      event.Comment,
+		}
+		competitor.GeneratedEvents = append(competitor.GeneratedEvents, dsqEvent)
+		s.logEvent(dsqEvent, true)
+
+	case EventTimePenalty:
+		if competitor.Status == StatusNotStarted || competitor.Status == StatusDisqualified {
+			s.warn(event, competitor.ID, "Competitor %d received a time penalty but is %s.", competitor.ID, competitor.Status)
+		}
+		competitor.TimeAdjustment += event.Penalty
+	}
+}
+
+// competitor returns the competitor with the given ID, creating them with
+// their roster attributes on their first event.
+func (s *Simulation) competitor(id int) *Competitor {
+	if c, ok := s.Competitors[id]; ok {
+		return c
+	}
+	c := GetOrCreateCompetitor(id, s.Competitors)
+	s.attachAttributes(c)
+	return c
+}
+
+func startCompetitor(competitor *Competitor, timestamp time.Time) {
+	competitor.ActualStartTime = timestamp
+	competitor.Status = StatusRacing
+	competitor.CurrentLapNumber = 1
+	competitor.CurrentLapTempData.LapStartTime = timestamp
+	if len(competitor.LapsData) == 0 {
+		lapData := LapRecord{
+			LapNumber:    competitor.CurrentLapNumber,
+			StartTime:    timestamp,
+			ShootingData: make([]ShootingRecord, 0),
+		}
+		competitor.LapsData = append(competitor.LapsData, lapData)
+	}
+}
+
+// setCourseStatus moves the competitor along the course. A jury
+// disqualification stands whatever the competitor does next, but their course
+// is still recorded so a reinstatement has the whole race.
+func setCourseStatus(competitor *Competitor, status CompetitorStatus) {
+	if competitor.Status == StatusDisqualified {
+		return
+	}
+	competitor.Status = status
+}
+
+// onFiringRange reports whether the competitor is shooting a stage, including
+// a disqualified competitor whose course is still being recorded.
+func onFiringRange(competitor *Competitor) bool {
+	return competitor.CurrentShooting != nil && (competitor.Status == StatusOnRange || competitor.Status == StatusDisqualified)
+}
+
+// resetRace drops everything recorded since the competitor's start, so a
+// repeated start event races them again from scratch.
+func resetRace(competitor *Competitor) {
+	competitor.FinishTime = time.Time{}
+	competitor.LapsData = nil
+	competitor.CurrentShooting = nil
+	competitor.CurrentLapTempData = Competitor{}.CurrentLapTempData
+	competitor.TotalHits = 0
+	competitor.TotalShots = 0
+	competitor.TotalPenaltiesServed = 0
+	competitor.DNFComment = ""
+}
+
+func hasShotOutcomes(shooting *ShootingRecord) bool {
+	for _, shot := range shooting.ShotSequence {
+		if shot.Outcome != ShotUnknown {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHitShot(shooting *ShootingRecord, target int) bool {
+	for _, shot := range shooting.ShotSequence {
+		if shot.Target == target && shot.Outcome == ShotHit {
+			return true
+		}
+	}
+	return false
+}
+
+// recordTargetHit counts a target hit event for the stage the competitor is
+// shooting. When the stage's shots have outcomes the hit only confirms a shot
+// that hit the target. Otherwise each target counts once however often its
+// hit is reported, and never more often than rounds were fired.
+func (s *Simulation) recordTargetHit(competitor *Competitor, event Event) {
+	session := &competitor.CurrentLapTempData
+	if hasShotOutcomes(competitor.CurrentShooting) {
+		if !hasHitShot(competitor.CurrentShooting, event.Target) {
+			s.warn(event, competitor.ID, "Competitor %d hit target %d but no shot hit it.", competitor.ID, event.Target)
+		}
+		return
+	}
+	if event.Target < 1 || event.Target > TargetsPerStage {
+		s.warn(event, competitor.ID, "Competitor %d hit target %d, targets are numbered 1 to %d.", competitor.ID, event.Target, TargetsPerStage)
+		return
+	}
+	if slices.Contains(session.TargetsHit, event.Target) {
+		s.warn(event, competitor.ID, "Competitor %d hit target %d more than once.", competitor.ID, event.Target)
+		return
+	}
+	if session.ShotsInSession > 0 && session.HitsInSession >= session.ShotsInSession {
+		s.warn(event, competitor.ID, "Competitor %d hit target %d but has only fired %d shots.", competitor.ID, event.Target, session.ShotsInSession)
+		return
+	}
+	session.TargetsHit = append(session.TargetsHit, event.Target)
+	session.HitsInSession++
+	competitor.TotalHits++
+}
+
+// countSessionShots brings TotalShots in line with the rounds counted so far
+// for the stage being shot, so the hits credited as they happen always have
+// their rounds. While the competitor is on the range only the rounds seen in
+// shot and hit events count; the stage's full outcome is counted on leaving.
+func (s *Simulation) countSessionShots(competitor *Competitor, shots int) {
+	session := &competitor.CurrentLapTempData
+	competitor.TotalShots += shots - session.ShotsCounted
+	session.ShotsCounted = shots
+}
+
+// shootingOutcome returns the rounds fired, spare rounds used and penalty laps
+// for the stage the competitor is leaving. Penalties are the targets still
+// standing once the spare rounds are used. When no shot events were recorded
+// the rounds are inferred: every loaded spare round was fired, and without
+// spare events in a relay every target left standing after the main rounds is
+// assumed to have been attacked with the spares.
+func (s *Simulation) shootingOutcome(competitor *Competitor) (shots, sparesUsed, penalties int) {
+	session := competitor.CurrentLapTempData
+	penalties = max(TargetsPerStage-session.HitsInSession, 0)
+
+	if session.ShotsInSession > 0 {
+		return max(session.ShotsInSession, session.HitsInSession), session.SparesInSession, penalties
+	}
+	if session.SparesInSession > 0 {
+		return TargetsPerStage + session.SparesInSession, session.SparesInSession, penalties
+	}
+
+	shots = TargetsPerStage
+	if s.Config.IsRelay() {
+		sparesUsed = min(penalties, RelaySpareRounds)
+		shots += sparesUsed
 	}
+	return shots, sparesUsed, penalties
 }
 
 func (s *Simulation) checkForNotStarted() {
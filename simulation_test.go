@@ -2,6 +2,9 @@ package main
 
 import (
 	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -168,3 +171,424 @@ func TestSimulation_NotFinished(t *testing.T) {
 		t.Errorf("DNFComment: got '%s', want 'Injured'", c.DNFComment)
 	}
 }
+
+func TestSimulation_PerShotEvents(t *testing.T) {
+	cfg := createTestConfig()
+	sim := NewSimulation(cfg)
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 5},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 5, FiringRange: 1},
+		{Timestamp: testTime(10, 5, 10, 0), ID: EventShotFired, CompetitorID: 5, Target: 1, Shot: ShotHit},
+		{Timestamp: testTime(10, 5, 13, 0), ID: EventShotFired, CompetitorID: 5, Target: 2, Shot: ShotMiss},
+		{Timestamp: testTime(10, 5, 16, 0), ID: EventShotFired, CompetitorID: 5, Target: 2, Shot: ShotHit},
+		{Timestamp: testTime(10, 5, 20, 0), ID: EventLeftFiringRange, CompetitorID: 5},
+	}
+	sim.Run(events)
+
+	c := sim.Competitors[5]
+	if c.TotalShots != 3 || c.TotalHits != 2 {
+		t.Errorf("Shooting totals: got %d/%d, want 2/3", c.TotalHits, c.TotalShots)
+	}
+	sr := c.LapsData[0].ShootingData[0]
+	if sr.RangeID != 1 {
+		t.Errorf("RangeID: got %d, want 1", sr.RangeID)
+	}
+	if got := sr.Sequence(); got != "X0X" {
+		t.Errorf("Sequence(): got '%s', want 'X0X'", got)
+	}
+	if got := sr.Rhythm(); got != 3*time.Second {
+		t.Errorf("Rhythm(): got %v, want 3s", got)
+	}
+	if math.Abs(sr.Accuracy()-2.0/3.0) > 0.001 {
+		t.Errorf("Accuracy(): got %f, want 0.667", sr.Accuracy())
+	}
+	if sr.PenaltiesIncurred != 3 {
+		t.Errorf("PenaltiesIncurred: got %d, want 3", sr.PenaltiesIncurred)
+	}
+	if c.CurrentShooting != nil {
+		t.Errorf("CurrentShooting should be cleared after leaving the range")
+	}
+}
+
+func TestSimulation_HitsKeptWhenStoppedOnRange(t *testing.T) {
+	sim := NewSimulation(createTestConfig())
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+		{Timestamp: testTime(10, 5, 2, 0), ID: EventTargetHit, CompetitorID: 1, Target: 1},
+		{Timestamp: testTime(10, 5, 4, 0), ID: EventTargetHit, CompetitorID: 1, Target: 2},
+		{Timestamp: testTime(10, 5, 6, 0), ID: EventCannotContinue, CompetitorID: 1, Comment: "Broken rifle"},
+	}
+	sim.Run(events)
+	sim.FinalizeResults()
+
+	c := sim.Competitors[1]
+	if c.TotalHits != 2 || c.TotalShots != 2 {
+		t.Errorf("Shooting totals: got %d/%d, want 2/2", c.TotalHits, c.TotalShots)
+	}
+	checkSimulationInvariants(t, sim)
+}
+
+func TestSimulation_JuryDecisions(t *testing.T) {
+	cfg := createTestConfig()
+	sim := NewSimulation(cfg)
+
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 3},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventJuryDisqualified, CompetitorID: 3, Comment: "Course cutting"},
+		{Timestamp: testTime(10, 20, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 19, 0, 0), ID: EventEndedMainLap, CompetitorID: 2},
+		{Timestamp: testTime(10, 18, 0, 0), ID: EventEndedMainLap, CompetitorID: 3},
+		{Timestamp: testTime(10, 25, 0, 0), ID: EventTimePenalty, CompetitorID: 2, Penalty: 2 * time.Minute, Comment: "Missed penalty loop"},
+	}
+	sim.Run(events)
+	sim.FinalizeResults()
+
+	dsq := sim.Competitors[3]
+	if dsq.Status != StatusDisqualified || dsq.DisqualificationReason != "Course cutting" {
+		t.Errorf("Competitor 3: got %s (%q), want Disqualified (Course cutting)", dsq.Status, dsq.DisqualificationReason)
+	}
+	if dsq.FinishTime.IsZero() {
+		t.Errorf("Competitor 3: finish time should still be recorded")
+	}
+	if len(dsq.GeneratedEvents) != 1 || dsq.GeneratedEvents[0].ID != EventDisqualified {
+		t.Errorf("Competitor 3: expected a single generated Disqualified event, got %+v", dsq.GeneratedEvents)
+	}
+
+	penalised := sim.Competitors[2]
+	if penalised.TimeAdjustment != 2*time.Minute {
+		t.Errorf("Competitor 2: TimeAdjustment got %v, want 2m", penalised.TimeAdjustment)
+	}
+	if got := penalised.GetOverallStatusForReport(cfg); got != "00:21:00.0" {
+		t.Errorf("Competitor 2: report result got %q, want 00:21:00.0", got)
+	}
+
+	ranking := RankCompetitors(sim.Competitors, cfg)
+	var order []int
+	for _, entry := range ranking {
+		order = append(order, entry.Competitor.ID)
+	}
+	if len(order) != 3 || order[0] != 1 || order[1] != 2 || order[2] != 3 {
+		t.Errorf("Ranking order: got %v, want [1 2 3]", order)
+	}
+}
+
+func TestSimulation_DisqualifiedCourseStillRecorded(t *testing.T) {
+	sim := NewSimulation(createTestConfig())
+	sim.Run([]Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+		{Timestamp: testTime(10, 5, 5, 0), ID: EventJuryDisqualified, CompetitorID: 1, Comment: "Unsafe rifle handling"},
+		{Timestamp: testTime(10, 5, 10, 0), ID: EventTargetHit, CompetitorID: 1, Target: 1},
+		{Timestamp: testTime(10, 5, 30, 0), ID: EventLeftFiringRange, CompetitorID: 1},
+		{Timestamp: testTime(10, 6, 0, 0), ID: EventEnteredPenaltyLaps, CompetitorID: 1},
+		{Timestamp: testTime(10, 8, 0, 0), ID: EventLeftPenaltyLaps, CompetitorID: 1},
+		{Timestamp: testTime(10, 9, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 10, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+	})
+
+	c := sim.Competitors[1]
+	if c.Status != StatusDisqualified {
+		t.Errorf("Status = %s, want %s", c.Status, StatusDisqualified)
+	}
+	if c.TotalHits != 1 || c.TotalShots != 5 || c.TotalPenaltiesServed != 4 {
+		t.Errorf("Course after the disqualification: got %d/%d and %d penalty laps, want 1/5 and 4", c.TotalHits, c.TotalShots, c.TotalPenaltiesServed)
+	}
+	if !c.ActualStartTime.Equal(testTime(10, 0, 0, 0)) || !c.FinishTime.Equal(testTime(10, 10, 0, 0)) {
+		t.Errorf("Got start %s and finish %s, want 10:00:00.000 and 10:10:00.000", FormatTime(c.ActualStartTime), FormatTime(c.FinishTime))
+	}
+}
+
+// checkSimulationInvariants verifies properties that hold after any run,
+// whatever events it was given.
+func checkSimulationInvariants(t *testing.T, sim *Simulation) {
+	t.Helper()
+	for _, c := range sim.Competitors {
+		for _, lap := range c.LapsData {
+			if lap.LapDuration < 0 {
+				t.Fatalf("Competitor %d lap %d duration = %v, want non-negative", c.ID, lap.LapNumber, lap.LapDuration)
+			}
+		}
+		for _, lap := range c.LapsData {
+			for _, sr := range lap.ShootingData {
+				if sr.Hits > sr.Shots {
+					t.Fatalf("Competitor %d has %d hits from %d shots on lap %d", c.ID, sr.Hits, sr.Shots, lap.LapNumber)
+				}
+			}
+		}
+		if c.TotalHits > c.TotalShots {
+			t.Fatalf("Competitor %d has %d hits from %d shots", c.ID, c.TotalHits, c.TotalShots)
+		}
+		if c.Status == StatusCompleted && len(c.LapsData) != sim.Config.Laps {
+			t.Fatalf("Competitor %d completed with %d laps, want %d", c.ID, len(c.LapsData), sim.Config.Laps)
+		}
+	}
+
+	sorted := SortCompetitors(sim.Competitors, sim.Config)
+	timing := sim.Config.Timing()
+	for i, a := range sorted {
+		if competitorLess(a, a, timing) {
+			t.Fatalf("competitorLess(%d, %d) is true", a.ID, a.ID)
+		}
+		for j, b := range sorted {
+			if i != j && competitorLess(a, b, timing) == competitorLess(b, a, timing) {
+				t.Fatalf("Competitors %d and %d are not strictly ordered", a.ID, b.ID)
+			}
+			if i < j && competitorLess(b, a, timing) {
+				t.Fatalf("Competitor %d is ranked after %d but orders before it", b.ID, a.ID)
+			}
+		}
+	}
+	ranks := RankSorted(sorted, timing)
+	for i := 1; i < len(ranks); i++ {
+		if ranks[i].Rank != 0 && ranks[i].Rank < ranks[i-1].Rank {
+			t.Fatalf("Rank %d follows rank %d", ranks[i].Rank, ranks[i-1].Rank)
+		}
+	}
+}
+
+func loadInputConfig(t testing.TB) *Config {
+	t.Helper()
+	config, err := LoadConfig(filepath.Join("input", "config.json"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	return config
+}
+
+func TestSimulation_InvariantsOnGeneratedRaces(t *testing.T) {
+	for _, discipline := range []string{"", "pursuit"} {
+		config := createTestConfig()
+		config.Laps = 3
+		config.FiringLines = 2
+		config.Discipline = discipline
+		profile := DefaultRaceProfile()
+		for seed := uint64(1); seed <= 20; seed++ {
+			sim := NewSimulation(config)
+			sim.Run(GenerateRace(config, 15, profile, seed))
+			sim.FinalizeResults()
+			checkSimulationInvariants(t, sim)
+		}
+	}
+}
+
+func FuzzSimulationRun(f *testing.F) {
+	data, err := os.ReadFile(filepath.Join("input", "events"))
+	if err != nil {
+		f.Fatalf("Failed to read sample events: %v", err)
+	}
+	f.Add(string(data), false)
+	f.Add(string(data), true)
+	f.Add("[10:00:00.000] 4 1\n[10:05:00.000] 10 1\n[10:10:00.000] 10 1", false)
+	f.Add("[10:00:00.000] 4 1\n[10:05:00.000] 10 1\n[10:10:00.000] 10 1\n[10:11:00.000] 4 1\n[10:12:00.000] 10 1", false)
+
+	config := loadInputConfig(f)
+	pursuit := *config
+	pursuit.Discipline = "pursuit"
+	f.Fuzz(func(t *testing.T, text string, pullsLapped bool) {
+		var events []Event
+		for i, line := range strings.Split(text, "\n") {
+			if event, _, ok := ParseEventLine(line, SourcePos{Line: i + 1}); ok {
+				events = append(events, event)
+			}
+		}
+		if len(events) > 1000 {
+			t.Skip("Too many events")
+		}
+
+		sim := NewSimulation(config)
+		if pullsLapped {
+			sim = NewSimulation(&pursuit)
+		}
+		sim.Run(events)
+		sim.FinalizeResults()
+		checkSimulationInvariants(t, sim)
+	})
+}
+
+func TestSimulation_ShotAndTargetHitEvents(t *testing.T) {
+	shot := func(sec, target int, outcome ShotOutcome) Event {
+		return Event{Timestamp: testTime(10, 5, sec, 0), ID: EventShotFired, CompetitorID: 1, Target: target, Shot: outcome}
+	}
+	hit := func(sec, target int) Event {
+		return Event{Timestamp: testTime(10, 5, sec, 0), ID: EventTargetHit, CompetitorID: 1, Target: target}
+	}
+	tests := []struct {
+		name          string
+		shooting      []Event
+		wantHits      int
+		wantShots     int
+		wantPenalties int
+	}{
+		{
+			name: "ShotsAndTargetHits",
+			shooting: []Event{
+				shot(1, 1, ShotHit), hit(1, 1),
+				shot(3, 2, ShotMiss),
+				shot(5, 3, ShotHit), hit(5, 3),
+				shot(7, 4, ShotMiss),
+				shot(9, 5, ShotHit), hit(9, 5),
+			},
+			wantHits: 3, wantShots: 5, wantPenalties: 2,
+		},
+		{
+			name: "TargetHitsBeforeShots",
+			shooting: []Event{
+				hit(1, 1), shot(1, 1, ShotHit),
+				hit(3, 2), shot(3, 2, ShotHit),
+				shot(5, 3, ShotMiss),
+				shot(7, 4, ShotMiss),
+				shot(9, 5, ShotMiss),
+			},
+			wantHits: 2, wantShots: 5, wantPenalties: 3,
+		},
+		{
+			name:     "TargetHitWithoutShot",
+			shooting: []Event{shot(1, 1, ShotMiss), hit(2, 1), shot(3, 2, ShotHit), hit(4, 2)},
+			wantHits: 1, wantShots: 2, wantPenalties: 4,
+		},
+		{
+			name:     "TargetHitsOnly",
+			shooting: []Event{hit(1, 1), hit(2, 2), hit(3, 3)},
+			wantHits: 3, wantShots: 5, wantPenalties: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := NewSimulation(createTestConfig())
+			events := []Event{
+				{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+				{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+			}
+			events = append(events, tt.shooting...)
+			events = append(events, Event{Timestamp: testTime(10, 5, 30, 0), ID: EventLeftFiringRange, CompetitorID: 1})
+			sim.Run(events)
+
+			c := sim.Competitors[1]
+			if c.TotalHits != tt.wantHits || c.TotalShots != tt.wantShots {
+				t.Errorf("Shooting totals: got %d/%d, want %d/%d", c.TotalHits, c.TotalShots, tt.wantHits, tt.wantShots)
+			}
+			sr := c.LapsData[0].ShootingData[0]
+			if sr.Hits != tt.wantHits || sr.PenaltiesIncurred != tt.wantPenalties {
+				t.Errorf("ShootingRecord: got %d hits and %d penalties, want %d and %d", sr.Hits, sr.PenaltiesIncurred, tt.wantHits, tt.wantPenalties)
+			}
+		})
+	}
+}
+
+func TestSimulation_TargetHitsWithoutShotOutcomes(t *testing.T) {
+	onRange := Event{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1}
+	leftRange := Event{Timestamp: testTime(10, 5, 30, 0), ID: EventLeftFiringRange, CompetitorID: 1}
+	hit := func(sec, target int) Event {
+		return Event{Timestamp: testTime(10, 5, sec, 0), ID: EventTargetHit, CompetitorID: 1, Target: target}
+	}
+	tests := []struct {
+		name         string
+		events       []Event
+		wantHits     int
+		wantShots    int
+		wantWarnings int
+	}{
+		{
+			name:      "EachTargetOnce",
+			events:    []Event{onRange, hit(1, 1), hit(2, 3), hit(3, 5), leftRange},
+			wantHits:  3,
+			wantShots: 5,
+		},
+		{
+			name:         "RepeatedTarget",
+			events:       []Event{onRange, hit(1, 1), hit(2, 1), hit(3, 2), hit(4, 2), leftRange},
+			wantHits:     2,
+			wantShots:    5,
+			wantWarnings: 2,
+		},
+		{
+			name:         "TargetOutOfRange",
+			events:       []Event{onRange, hit(1, 0), hit(2, 6), hit(3, 4), leftRange},
+			wantHits:     1,
+			wantShots:    5,
+			wantWarnings: 2,
+		},
+		{
+			name: "MoreHitsThanShots",
+			events: []Event{
+				onRange,
+				{Timestamp: testTime(10, 5, 1, 0), ID: EventShotFired, CompetitorID: 1},
+				hit(2, 1), hit(3, 2),
+				leftRange,
+			},
+			wantHits:     1,
+			wantShots:    1,
+			wantWarnings: 1,
+		},
+		{
+			name: "HitOutsideFiringRange",
+			events: []Event{
+				{Timestamp: testTime(10, 4, 50, 0), ID: EventTargetHit, CompetitorID: 1, Target: 1},
+				onRange, hit(2, 2), leftRange, hit(40, 3),
+			},
+			wantHits:     1,
+			wantShots:    5,
+			wantWarnings: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := NewSimulation(createTestConfig())
+			events := append([]Event{{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1}}, tt.events...)
+			sim.Run(events)
+
+			c := sim.Competitors[1]
+			if c.TotalHits != tt.wantHits || c.TotalShots != tt.wantShots {
+				t.Errorf("Shooting totals: got %d/%d, want %d/%d", c.TotalHits, c.TotalShots, tt.wantHits, tt.wantShots)
+			}
+			warnings := 0
+			for _, entry := range sim.OutputLog {
+				if entry.Severity == SeverityWarning {
+					warnings++
+				}
+			}
+			if warnings != tt.wantWarnings {
+				t.Errorf("Got %d warnings, want %d", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestSimulation_RepeatedStartRestarts(t *testing.T) {
+	sim := NewSimulation(createTestConfig())
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 3, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+		{Timestamp: testTime(10, 3, 10, 0), ID: EventTargetHit, CompetitorID: 1, Target: 1},
+		{Timestamp: testTime(10, 3, 30, 0), ID: EventLeftFiringRange, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+		{Timestamp: testTime(10, 6, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 8, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+	}
+	sim.Run(events)
+	sim.FinalizeResults()
+
+	c := sim.Competitors[1]
+	if !c.ActualStartTime.Equal(testTime(10, 6, 0, 0)) {
+		t.Errorf("ActualStartTime = %s, want 10:06:00.000", FormatTime(c.ActualStartTime))
+	}
+	if len(c.LapsData) != 1 {
+		t.Fatalf("Got %d laps, want 1", len(c.LapsData))
+	}
+	if lap := c.LapsData[0]; !lap.StartTime.Equal(c.ActualStartTime) || lap.LapDuration != 2*time.Minute {
+		t.Errorf("Lap 1 starts at %s and lasts %v, want the restart and 2m0s", FormatTime(lap.StartTime), lap.LapDuration)
+	}
+	if c.TotalHits != 0 || c.TotalShots != 0 || len(c.LapsData[0].ShootingData) != 0 {
+		t.Errorf("Shooting before the restart kept: %d/%d", c.TotalHits, c.TotalShots)
+	}
+	if c.Status != StatusCompleted {
+		t.Errorf("Status = %s, want %s", c.Status, StatusCompleted)
+	}
+	checkSimulationInvariants(t, sim)
+}
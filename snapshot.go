@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+const SnapshotVersion = 1
+
+// EventPosition identifies the last event a simulation processed. Index is
+// the number of events processed so far, which is also the position in the
+// time-ordered events log to continue from.
+type EventPosition struct {
+	Index        int       `json:"index"`
+	Time         time.Time `json:"time"`
+	ID           EventID   `json:"eventId"`
+	CompetitorID int       `json:"competitorId"`
+	Source       SourcePos `json:"source"`
+}
+
+func (p EventPosition) matches(event Event) bool {
+	return p.Time.Equal(event.Timestamp) && p.ID == event.ID && p.CompetitorID == event.CompetitorID
+}
+
+func (p EventPosition) String() string {
+	if p.Source.Line > 0 {
+		return fmt.Sprintf("[%s] %d %d at %s", FormatTime(p.Time), p.ID, p.CompetitorID, p.Source)
+	}
+	return fmt.Sprintf("[%s] %d %d", FormatTime(p.Time), p.ID, p.CompetitorID)
+}
+
+// SimulationSnapshot is the full state of a simulation part way through a
+// race. It does not include the configuration, which is loaded again when
+// the simulation is restored.
+type SimulationSnapshot struct {
+	Version     int           `json:"version"`
+	Position    EventPosition `json:"position"`
+	Competitors []*Competitor `json:"competitors"`
+	OutputLog   []LogEntry    `json:"outputLog"`
+}
+
+// Snapshot captures the simulation state. The snapshot shares the
+// simulation's data, so it should be written before more events are
+// processed.
+func (s *Simulation) Snapshot() *SimulationSnapshot {
+	competitors := make([]*Competitor, 0, len(s.Competitors))
+	for _, c := range s.Competitors {
+		competitors = append(competitors, c)
+	}
+	sort.Slice(competitors, func(i, j int) bool {
+		return competitors[i].ID < competitors[j].ID
+	})
+	return &SimulationSnapshot{
+		Version:     SnapshotVersion,
+		Position:    s.Position,
+		Competitors: competitors,
+		OutputLog:   s.OutputLog,
+	}
+}
+
+// RestoreSimulation rebuilds a simulation from a snapshot taken with the
+// same configuration.
+func RestoreSimulation(config *Config, snapshot *SimulationSnapshot) *Simulation {
+	s := NewSimulation(config)
+	for _, c := range snapshot.Competitors {
+		s.Competitors[c.ID] = c
+	}
+	s.OutputLog = append(s.OutputLog, snapshot.OutputLog...)
+	s.Position = snapshot.Position
+	return s
+}
+
+func WriteSnapshot(w io.Writer, snapshot *SimulationSnapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(snapshot); err != nil {
+		return fmt.Errorf("failed to write simulation snapshot: %w", err)
+	}
+	return nil
+}
+
+func ReadSnapshot(r io.Reader) (*SimulationSnapshot, error) {
+	var snapshot SimulationSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to read simulation snapshot: %w", err)
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported simulation snapshot version %d", snapshot.Version)
+	}
+	return &snapshot, nil
+}
+
+// Advance processes the events of the log that come after the simulation's
+// position, in time order, up to and including until; a zero until processes
+// them all. The log must be the one the simulation has processed so far,
+// possibly with later events added.
+func (s *Simulation) Advance(events []Event, until time.Time) error {
+	sorted := make([]Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	pos := s.Position.Index
+	if pos > len(sorted) {
+		return fmt.Errorf("the events log has %d events but the simulation already processed %d", len(sorted), pos)
+	}
+	if pos > 0 && !s.Position.matches(sorted[pos-1]) {
+		return fmt.Errorf("event %d of the log is %s, not the last processed event %s", pos, FormatEventLine(sorted[pos-1]), s.Position)
+	}
+
+	for _, event := range sorted[pos:] {
+		if !until.IsZero() && event.Timestamp.After(until) {
+			break
+		}
+		s.processEvent(event)
+	}
+	return nil
+}
+
+// Resume processes the rest of the events log after the simulation's
+// position and closes the race like Run does.
+func (s *Simulation) Resume(events []Event) error {
+	if err := s.Advance(events, time.Time{}); err != nil {
+		return err
+	}
+	s.checkForNotStarted()
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func raceOutput(t *testing.T, simulation *Simulation) string {
+	t.Helper()
+	var buf bytes.Buffer
+	GenerateOutputLog(&buf, simulation.OutputLog)
+	printFinalReports(&buf, simulation, simulation.Config)
+	return buf.String()
+}
+
+func TestSnapshot_ResumeMatchesUninterruptedRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		discipline string
+		seed       uint64
+	}{
+		{name: "Sprint", seed: 3},
+		{name: "Pursuit", discipline: "pursuit", seed: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := createTestConfig()
+			config.Laps = 3
+			config.FiringLines = 2
+			config.Discipline = tt.discipline
+			events := GenerateRace(config, 12, DefaultRaceProfile(), tt.seed)
+
+			uninterrupted := NewSimulation(config)
+			uninterrupted.Run(append([]Event(nil), events...))
+			uninterrupted.FinalizeResults()
+			want := raceOutput(t, uninterrupted)
+
+			sorted := append([]Event(nil), events...)
+			sort.SliceStable(sorted, func(i, j int) bool {
+				return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+			})
+			for cut := 0; cut < len(sorted); cut += len(sorted)/7 + 1 {
+				// Only the events up to the cut have arrived when the
+				// snapshot is taken.
+				var arrived []Event
+				for _, event := range events {
+					if !event.Timestamp.After(sorted[cut].Timestamp) {
+						arrived = append(arrived, event)
+					}
+				}
+				partial := NewSimulation(config)
+				if err := partial.Advance(arrived, sorted[cut].Timestamp); err != nil {
+					t.Fatalf("Advance() error = %v", err)
+				}
+
+				var buf bytes.Buffer
+				if err := WriteSnapshot(&buf, partial.Snapshot()); err != nil {
+					t.Fatalf("WriteSnapshot() error = %v", err)
+				}
+				snapshot, err := ReadSnapshot(&buf)
+				if err != nil {
+					t.Fatalf("ReadSnapshot() error = %v", err)
+				}
+				if !reflect.DeepEqual(snapshot.Competitors, partial.Snapshot().Competitors) {
+					t.Fatalf("Competitors changed in the snapshot round trip at event %d", cut)
+				}
+
+				resumed := RestoreSimulation(config, snapshot)
+				if err := resumed.Resume(events); err != nil {
+					t.Fatalf("Resume() error = %v", err)
+				}
+				resumed.FinalizeResults()
+				if got := raceOutput(t, resumed); got != want {
+					t.Fatalf("Resumed at event %d output differs:\n--- got\n%s\n--- want\n%s", cut, got, want)
+				}
+				if resumed.Position != uninterrupted.Position {
+					t.Errorf("Resumed position = %v, want %v", resumed.Position, uninterrupted.Position)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshot_KeepsRaceInProgress(t *testing.T) {
+	config := createTestConfig()
+	sim := NewSimulation(config)
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventOnFiringRange, CompetitorID: 1, FiringRange: 1},
+		{Timestamp: testTime(10, 5, 2, 0), ID: EventShotFired, CompetitorID: 1, Target: 1, Shot: ShotHit},
+		{Timestamp: testTime(10, 5, 4, 0), ID: EventShotFired, CompetitorID: 1, Target: 2, Shot: ShotMiss},
+	}
+	if err := sim.Advance(events, testTime(10, 5, 4, 0)); err != nil {
+		t.Fatalf("Advance() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, sim.Snapshot()); err != nil {
+		t.Fatalf("WriteSnapshot() error = %v", err)
+	}
+	snapshot, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("ReadSnapshot() error = %v", err)
+	}
+	restored := RestoreSimulation(config, snapshot)
+
+	c := restored.Competitors[1]
+	if c.Status != StatusOnRange || c.CurrentShooting == nil || len(c.CurrentShooting.ShotSequence) != 2 {
+		t.Fatalf("Restored competitor = %+v, want on the range with 2 shots", c)
+	}
+	if c.CurrentLapTempData.ShotsInSession != 2 || c.CurrentLapTempData.HitsInSession != 1 {
+		t.Errorf("Restored session = %+v, want 2 shots and 1 hit", c.CurrentLapTempData)
+	}
+	if !reflect.DeepEqual(restored.OutputLog, sim.OutputLog) {
+		t.Errorf("Restored log = %v, want %v", restored.OutputLog, sim.OutputLog)
+	}
+	if restored.Position.Index != 4 || !restored.Position.Time.Equal(testTime(10, 5, 4, 0)) {
+		t.Errorf("Restored position = %v, want event 4 at 10:05:04", restored.Position)
+	}
+}
+
+func TestSnapshot_Errors(t *testing.T) {
+	config := createTestConfig()
+	events := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 1},
+		{Timestamp: testTime(10, 5, 0, 0), ID: EventEndedMainLap, CompetitorID: 1},
+	}
+	sim := NewSimulation(config)
+	if err := sim.Advance(events, testTime(10, 0, 0, 0)); err != nil {
+		t.Fatalf("Advance() error = %v", err)
+	}
+
+	t.Run("DifferentLog", func(t *testing.T) {
+		other := []Event{
+			{Timestamp: testTime(10, 0, 0, 0), ID: EventStarted, CompetitorID: 2},
+			{Timestamp: testTime(10, 5, 0, 0), ID: EventEndedMainLap, CompetitorID: 2},
+		}
+		if err := RestoreSimulation(config, sim.Snapshot()).Resume(other); err == nil {
+			t.Fatal("Resume() expected an error for a different events log")
+		}
+	})
+
+	t.Run("ShorterLog", func(t *testing.T) {
+		if err := RestoreSimulation(config, sim.Snapshot()).Resume(nil); err == nil {
+			t.Fatal("Resume() expected an error for an events log shorter than the snapshot")
+		}
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		_, err := ReadSnapshot(strings.NewReader(`{"version": 99}`))
+		if err == nil || !strings.Contains(err.Error(), "version") {
+			t.Fatalf("ReadSnapshot() error = %v, want a version error", err)
+		}
+	})
+}
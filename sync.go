@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+const DefaultSyncWindow = 5 * time.Second
+
+// SyncEstimate is the clock model fitted for one source against the reference
+// source: a reference time is estimated as t + Offset + Drift*(t - Epoch).
+// Residuals are what is left of the differences on the matched events after
+// the correction.
+type SyncEstimate struct {
+	Source      string
+	Reference   string
+	Pairs       int
+	Epoch       time.Time
+	Offset      time.Duration
+	Drift       float64
+	RMSResidual time.Duration
+	MaxResidual time.Duration
+}
+
+// Correct maps a time read from the source's clock onto the reference clock.
+func (e SyncEstimate) Correct(t time.Time) time.Time {
+	if e.Pairs == 0 {
+		return t
+	}
+	elapsed := t.Sub(e.Epoch).Seconds()
+	return t.Add(e.Offset + time.Duration(e.Drift*elapsed*float64(time.Second)))
+}
+
+// DriftPPM is the drift in microseconds per second, the unit timing devices
+// are usually specified in.
+func (e SyncEstimate) DriftPPM() float64 {
+	return e.Drift * 1e6
+}
+
+// syncPair is an event seen by both the reference and the synchronized source.
+type syncPair struct {
+	reference time.Time
+	source    time.Time
+}
+
+// matchSyncPairs pairs each source event with the nearest unused reference
+// event of the same kind, competitor and parameters within window. Sync
+// pulses and shared events such as lap ends are matched the same way.
+func matchSyncPairs(reference, source []Event, window time.Duration) []syncPair {
+	byKey := make(map[duplicateKey][]Event)
+	for _, event := range reference {
+		key := duplicateKey{event.ID, event.CompetitorID, event.ExtraParamsStr}
+		byKey[key] = append(byKey[key], event)
+	}
+	used := make(map[*Event]bool)
+
+	var pairs []syncPair
+	for _, event := range source {
+		candidates := byKey[duplicateKey{event.ID, event.CompetitorID, event.ExtraParamsStr}]
+		var best *Event
+		var bestGap time.Duration
+		for i := range candidates {
+			candidate := &candidates[i]
+			gap := candidate.Timestamp.Sub(event.Timestamp)
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap <= window && !used[candidate] && (best == nil || gap < bestGap) {
+				best, bestGap = candidate, gap
+			}
+		}
+		if best != nil {
+			used[best] = true
+			pairs = append(pairs, syncPair{reference: best.Timestamp, source: event.Timestamp})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].source.Before(pairs[j].source) })
+	return pairs
+}
+
+// EstimateSync fits offset and drift of source against reference by least
+// squares over the matched events. One pair gives an offset only; with no
+// pairs the estimate leaves times unchanged.
+func EstimateSync(reference, source []Event, window time.Duration) SyncEstimate {
+	pairs := matchSyncPairs(reference, source, window)
+	estimate := SyncEstimate{Pairs: len(pairs)}
+	if len(pairs) == 0 {
+		return estimate
+	}
+	estimate.Epoch = pairs[0].source
+
+	n := float64(len(pairs))
+	var sumX, sumY, sumXX, sumXY float64
+	for _, p := range pairs {
+		x := p.source.Sub(estimate.Epoch).Seconds()
+		y := p.reference.Sub(p.source).Seconds()
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	offset := sumY / n
+	if denominator := n*sumXX - sumX*sumX; len(pairs) > 1 && denominator > 1e-9 {
+		estimate.Drift = (n*sumXY - sumX*sumY) / denominator
+		offset = (sumY - estimate.Drift*sumX) / n
+	}
+	estimate.Offset = time.Duration(math.Round(offset * float64(time.Second)))
+
+	var sumSquares float64
+	for _, p := range pairs {
+		residual := p.reference.Sub(estimate.Correct(p.source))
+		sumSquares += residual.Seconds() * residual.Seconds()
+		if residual < 0 {
+			residual = -residual
+		}
+		estimate.MaxResidual = max(estimate.MaxResidual, residual)
+	}
+	estimate.RMSResidual = time.Duration(math.Sqrt(sumSquares/n) * float64(time.Second))
+	return estimate
+}
+
+// SynchronizeSources rewrites the timestamps of every source after the first
+// onto the first source's clock and returns the estimate used for each.
+func SynchronizeSources(perSource [][]Event, names []string, window time.Duration) []SyncEstimate {
+	if window <= 0 {
+		window = DefaultSyncWindow
+	}
+	var estimates []SyncEstimate
+	for i := 1; i < len(perSource); i++ {
+		estimate := EstimateSync(perSource[0], perSource[i], window)
+		estimate.Source = names[i]
+		estimate.Reference = names[0]
+		for j := range perSource[i] {
+			event := &perSource[i][j]
+			corrected := estimate.Correct(event.Timestamp)
+			event.ClockOffset += corrected.Sub(event.Timestamp)
+			event.Timestamp = corrected
+		}
+		estimates = append(estimates, estimate)
+	}
+	return estimates
+}
+
+func WriteSyncReport(w io.Writer, estimates []SyncEstimate) error {
+	for _, e := range estimates {
+		var err error
+		if e.Pairs == 0 {
+			_, err = fmt.Fprintf(w, "Clock sync %s -> %s: no shared events, not corrected\n", e.Source, e.Reference)
+		} else {
+			_, err = fmt.Fprintf(w, "Clock sync %s -> %s: %d events, offset %+.3fs, drift %+.1f ppm, residual rms %.3fs max %.3fs\n",
+				e.Source, e.Reference, e.Pairs, e.Offset.Seconds(), e.DriftPPM(), e.RMSResidual.Seconds(), e.MaxResidual.Seconds())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
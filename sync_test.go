@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateSync(t *testing.T) {
+	const drift = 200e-6
+	offset := 1500 * time.Millisecond
+	epoch := testTime(10, 0, 0, 0)
+
+	var reference, source []Event
+	for i := 0; i < 6; i++ {
+		sourceTime := epoch.Add(time.Duration(i) * 5 * time.Minute)
+		elapsed := sourceTime.Sub(epoch).Seconds()
+		referenceTime := sourceTime.Add(offset + time.Duration(drift*elapsed*float64(time.Second)))
+		key := Event{ID: EventEndedMainLap, CompetitorID: i + 1}
+		if i == 5 {
+			key = Event{ID: EventSyncPulse, ExtraParamsStr: "P1"}
+		}
+		ref, src := key, key
+		ref.Timestamp, src.Timestamp = referenceTime, sourceTime
+		reference = append(reference, ref)
+		source = append(source, src)
+	}
+	// Unshared and far-off events must not be paired.
+	source = append(source, Event{Timestamp: epoch, ID: EventStarted, CompetitorID: 1})
+	reference = append(reference, Event{Timestamp: epoch.Add(time.Minute), ID: EventEndedMainLap, CompetitorID: 1})
+
+	estimate := EstimateSync(reference, source, DefaultSyncWindow)
+	if estimate.Pairs != 6 {
+		t.Fatalf("Pairs: got %d, want 6", estimate.Pairs)
+	}
+	if d := estimate.Offset - offset; d < -time.Millisecond || d > time.Millisecond {
+		t.Errorf("Offset: got %v, want %v", estimate.Offset, offset)
+	}
+	if math.Abs(estimate.DriftPPM()-200) > 1 {
+		t.Errorf("Drift: got %.2f ppm, want 200", estimate.DriftPPM())
+	}
+	if estimate.MaxResidual > time.Millisecond {
+		t.Errorf("MaxResidual: got %v, want below 1ms", estimate.MaxResidual)
+	}
+}
+
+func TestEstimateSync_SinglePairAndNoPairs(t *testing.T) {
+	reference := []Event{{Timestamp: testTime(10, 0, 2, 0), ID: EventSyncPulse, ExtraParamsStr: "A"}}
+	source := []Event{{Timestamp: testTime(10, 0, 0, 0), ID: EventSyncPulse, ExtraParamsStr: "A"}}
+
+	single := EstimateSync(reference, source, DefaultSyncWindow)
+	if single.Pairs != 1 || single.Offset != 2*time.Second || single.Drift != 0 {
+		t.Errorf("Single pair: got %+v, want a 2s offset without drift", single)
+	}
+
+	none := EstimateSync(reference, source, time.Second)
+	if none.Pairs != 0 || !none.Correct(testTime(10, 0, 0, 0)).Equal(testTime(10, 0, 0, 0)) {
+		t.Errorf("No pairs: expected times to be left unchanged, got %+v", none)
+	}
+}
+
+func TestSynchronizeSources(t *testing.T) {
+	start := []Event{
+		{Timestamp: testTime(10, 0, 0, 0), ID: EventSyncPulse, ExtraParamsStr: "1"},
+		{Timestamp: testTime(10, 30, 0, 0), ID: EventSyncPulse, ExtraParamsStr: "2"},
+	}
+	finish := []Event{
+		{Timestamp: testTime(9, 59, 59, 0), ID: EventSyncPulse, ExtraParamsStr: "1"},
+		{Timestamp: testTime(10, 29, 59, 0), ID: EventSyncPulse, ExtraParamsStr: "2"},
+		{Timestamp: testTime(10, 20, 0, 0), ID: EventEndedMainLap, CompetitorID: 3, ClockOffset: 500 * time.Millisecond},
+	}
+
+	estimates := SynchronizeSources([][]Event{start, finish}, []string{"start", "finish"}, 0)
+	if len(estimates) != 1 || estimates[0].Source != "finish" || estimates[0].Reference != "start" {
+		t.Fatalf("Unexpected estimates: %+v", estimates)
+	}
+	lapEnd := finish[2]
+	if !lapEnd.Timestamp.Equal(testTime(10, 20, 1, 0)) || lapEnd.ClockOffset != 1500*time.Millisecond {
+		t.Errorf("Expected the finish clock to be moved forward 1s, got %s (offset %v)", FormatTime(lapEnd.Timestamp), lapEnd.ClockOffset)
+	}
+
+	var report strings.Builder
+	WriteSyncReport(&report, estimates)
+	if !strings.Contains(report.String(), "Clock sync finish -> start: 2 events, offset +1.000s, drift +0.0 ppm") {
+		t.Errorf("Unexpected report: %q", report.String())
+	}
+}
+
+func TestSimulation_IgnoresSyncPulses(t *testing.T) {
+	sim := NewSimulation(createTestConfig())
+	sim.Run([]Event{{Timestamp: testTime(10, 0, 0, 0), ID: EventSyncPulse, ExtraParamsStr: "P1"}})
+	if len(sim.Competitors) != 0 {
+		t.Errorf("Sync pulse should not create a competitor, got %d", len(sim.Competitors))
+	}
+	if len(sim.OutputLog) != 1 || sim.OutputLog[0].Message != "Timing sync pulse P1" {
+		t.Errorf("Expected the pulse in the log, got %v", sim.OutputLog)
+	}
+}
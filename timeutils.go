@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
@@ -49,7 +50,18 @@ func ParseDuration(durationStr string) (time.Duration, error) {
 		return 0, fmt.Errorf("invalid seconds in duration '%s': %w", durationStr, err)
 	}
 
-	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second, nil
+	const maxSeconds = int64(math.MaxInt64 / int64(time.Second))
+	inRange := func(v int, unit int64) bool {
+		return int64(v) <= maxSeconds/unit && int64(v) >= -maxSeconds/unit
+	}
+	if !inRange(h, 3600) || !inRange(m, 60) || !inRange(sec, 1) {
+		return 0, fmt.Errorf("duration '%s' is out of range", durationStr)
+	}
+	seconds := int64(h)*3600 + int64(m)*60 + int64(sec)
+	if seconds > maxSeconds || seconds < -maxSeconds {
+		return 0, fmt.Errorf("duration '%s' is out of range", durationStr)
+	}
+	return time.Duration(seconds) * time.Second, nil
 }
 
 func FormatDuration(d time.Duration) string {
@@ -70,3 +82,37 @@ func FormatDuration(d time.Duration) string {
 func FormatTime(t time.Time) string {
 	return t.Format(TimeLayout)
 }
+
+// TimingPolicy describes how official times are derived from raw timestamps:
+// the precision they are kept at and whether the remainder is truncated or
+// rounded.
+type TimingPolicy struct {
+	Precision time.Duration
+	Round     bool
+}
+
+func (p TimingPolicy) Apply(d time.Duration) time.Duration {
+	if p.Precision <= 0 {
+		return d
+	}
+	if p.Round {
+		return d.Round(p.Precision)
+	}
+	return d.Truncate(p.Precision)
+}
+
+// Format renders a duration as HH:MM:SS followed by as many decimals as the
+// precision allows, e.g. "00:25:00.1" for tenths.
+func (p TimingPolicy) Format(d time.Duration) string {
+	formatted := FormatDuration(p.Apply(d))
+	switch {
+	case p.Precision >= time.Second:
+		return formatted[:len(formatted)-4]
+	case p.Precision >= 100*time.Millisecond:
+		return formatted[:len(formatted)-2]
+	case p.Precision >= 10*time.Millisecond:
+		return formatted[:len(formatted)-1]
+	default:
+		return formatted
+	}
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 )
@@ -52,6 +55,7 @@ func TestParseDuration(t *testing.T) {
 		{"ValidDurationComplex", "01:10:05", 1*time.Hour + 10*time.Minute + 5*time.Second, false},
 		{"InvalidFormat", "00:30", 0, true},
 		{"InvalidNumber", "00:AA:30", 0, true},
+		{"OutOfRange", "7000000:00:00", 0, true},
 	}
 
 	for _, tt := range tests {
@@ -102,3 +106,87 @@ func TestFormatTime(t *testing.T) {
 		t.Errorf("FormatTime() = %v, want %v", got, expectedNoMillis)
 	}
 }
+
+func TestTimingPolicy(t *testing.T) {
+	d := 1*time.Hour + 2*time.Minute + 3*time.Second + 456*time.Millisecond
+	tests := []struct {
+		name       string
+		policy     TimingPolicy
+		wantApply  time.Duration
+		wantFormat string
+	}{
+		{"TruncateTenths", TimingPolicy{Precision: 100 * time.Millisecond}, d - 56*time.Millisecond, "01:02:03.4"},
+		{"RoundTenths", TimingPolicy{Precision: 100 * time.Millisecond, Round: true}, d + 44*time.Millisecond, "01:02:03.5"},
+		{"TruncateSeconds", TimingPolicy{Precision: time.Second}, d - 456*time.Millisecond, "01:02:03"},
+		{"RoundHundredths", TimingPolicy{Precision: 10 * time.Millisecond, Round: true}, d + 4*time.Millisecond, "01:02:03.46"},
+		{"Milliseconds", TimingPolicy{Precision: time.Millisecond}, d, "01:02:03.456"},
+		{"NoPrecision", TimingPolicy{}, d, "01:02:03.456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Apply(d); got != tt.wantApply {
+				t.Errorf("Apply() = %v, want %v", got, tt.wantApply)
+			}
+			if got := tt.policy.Format(d); got != tt.wantFormat {
+				t.Errorf("Format() = %v, want %v", got, tt.wantFormat)
+			}
+		})
+	}
+}
+
+// inputSeeds returns every substring of the files under input/ that matches
+// the pattern.
+func inputSeeds(f *testing.F, pattern string) []string {
+	f.Helper()
+	var seeds []string
+	for _, name := range []string{"events", "config.json"} {
+		data, err := os.ReadFile(filepath.Join("input", name))
+		if err != nil {
+			f.Fatalf("Failed to read %s: %v", name, err)
+		}
+		seeds = append(seeds, regexp.MustCompile(pattern).FindAllString(string(data), -1)...)
+	}
+	return seeds
+}
+
+func FuzzParseTime(f *testing.F) {
+	for _, seed := range inputSeeds(f, `\d{2}:\d{2}:\d{2}(\.\d{3})?`) {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, timeStr string) {
+		got, err := ParseTime(timeStr)
+		if fast, ok := parseClock(timeStr); ok && (err != nil || !fast.Equal(got)) {
+			t.Fatalf("parseClock(%q) = %v, ParseTime() = %v, %v", timeStr, fast, got, err)
+		}
+		if err != nil {
+			return
+		}
+		if y, m, d := got.Date(); y != projectEpoch.Year() || m != projectEpoch.Month() || d != projectEpoch.Day() || got.Location() != time.UTC {
+			t.Fatalf("ParseTime(%q) = %v, want a time on %v", timeStr, got, projectEpoch)
+		}
+		again, err := ParseTime(FormatTime(got))
+		if err != nil || !again.Equal(got.Truncate(time.Millisecond)) {
+			t.Fatalf("ParseTime(FormatTime(%v)) = %v, %v", got, again, err)
+		}
+	})
+}
+
+func FuzzParseDuration(f *testing.F) {
+	for _, seed := range inputSeeds(f, `"\d{2}:\d{2}:\d{2}"`) {
+		f.Add(seed[1 : len(seed)-1])
+	}
+	f.Fuzz(func(t *testing.T, durationStr string) {
+		got, err := ParseDuration(durationStr)
+		if err != nil || got < 0 {
+			return
+		}
+		if got%time.Second != 0 {
+			t.Fatalf("ParseDuration(%q) = %v, want whole seconds", durationStr, got)
+		}
+		again, err := ParseDuration(FormatDuration(got))
+		if err != nil || again != got {
+			t.Fatalf("ParseDuration(FormatDuration(%v)) = %v, %v", got, again, err)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// eventTokens holds the fields of an events file line as byte ranges into the
+// line, so tokenizing allocates nothing. A field that is absent has a start
+// of -1; params is only present when whitespace follows the competitor ID.
+type eventTokens struct {
+	timeStart, timeEnd             int
+	idStart, idEnd                 int
+	competitorStart, competitorEnd int
+	paramsStart, paramsEnd         int
+}
+
+// isEventSpace reports whether c separates the fields of an events line.
+func isEventSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// stripSourcePrefix removes a leading source marker, a backslash followed by
+// any number of 's', from a line.
+func stripSourcePrefix(line string) string {
+	if line == "" || line[0] != '\\' {
+		return line
+	}
+	i := 1
+	for i < len(line) && line[i] == 's' {
+		i++
+	}
+	return line[i:]
+}
+
+// tokenizeEventLine splits "[HH:MM:SS.sss] <eventID> <competitorID> [params]"
+// into its fields. It reports false when the line does not have that shape.
+func tokenizeEventLine(line string) (eventTokens, bool) {
+	const clockLen = len("00:00:00.000")
+	tokens := eventTokens{paramsStart: -1, paramsEnd: -1}
+
+	if len(line) < clockLen+2 || line[0] != '[' || line[clockLen+1] != ']' {
+		return tokens, false
+	}
+	for i := 1; i <= clockLen; i++ {
+		switch i {
+		case 3, 6:
+			if line[i] != ':' {
+				return tokens, false
+			}
+		case 9:
+			if line[i] != '.' {
+				return tokens, false
+			}
+		default:
+			if !isDigit(line[i]) {
+				return tokens, false
+			}
+		}
+	}
+	tokens.timeStart, tokens.timeEnd = 1, clockLen+1
+	pos := clockLen + 2
+
+	number := func() (int, int, bool) {
+		spaceStart := pos
+		for pos < len(line) && isEventSpace(line[pos]) {
+			pos++
+		}
+		start := pos
+		for pos < len(line) && isDigit(line[pos]) {
+			pos++
+		}
+		return start, pos, start > spaceStart && pos > start
+	}
+	var ok bool
+	if tokens.idStart, tokens.idEnd, ok = number(); !ok {
+		return tokens, false
+	}
+	if tokens.competitorStart, tokens.competitorEnd, ok = number(); !ok {
+		return tokens, false
+	}
+	if pos == len(line) {
+		return tokens, true
+	}
+	if !isEventSpace(line[pos]) {
+		return tokens, false
+	}
+
+	for pos < len(line) && isEventSpace(line[pos]) {
+		pos++
+	}
+	if strings.IndexByte(line[pos:], '\n') >= 0 {
+		return tokens, false
+	}
+	tokens.paramsStart, tokens.paramsEnd = pos, len(line)
+	return tokens, true
+}
+
+// parseClock parses an "HH:MM:SS.sss" time on the project epoch without
+// allocating. It reports false for anything ParseTime would have to reject
+// or parse the slow way.
+func parseClock(s string) (time.Time, bool) {
+	if len(s) != len("00:00:00.000") || s[2] != ':' || s[5] != ':' || s[8] != '.' {
+		return time.Time{}, false
+	}
+	digits := func(from, to int) (int, bool) {
+		n := 0
+		for i := from; i < to; i++ {
+			if !isDigit(s[i]) {
+				return 0, false
+			}
+			n = n*10 + int(s[i]-'0')
+		}
+		return n, true
+	}
+	h, okH := digits(0, 2)
+	m, okM := digits(3, 5)
+	sec, okS := digits(6, 8)
+	ms, okMs := digits(9, 12)
+	if !okH || !okM || !okS || !okMs || h > 23 || m > 59 || sec > 59 {
+		return time.Time{}, false
+	}
+	return time.Date(projectEpoch.Year(), projectEpoch.Month(), projectEpoch.Day(), h, m, sec, ms*int(time.Millisecond), time.UTC), true
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var referenceEventRegex = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2}\.\d{3})\]\s+(\d+)\s+(\d+)(?:\s+(.*))?$`)
+var referenceSourcePrefixRegex = regexp.MustCompile(`^\\s*`)
+
+// parseEventLineRegexp is the regular expression parser the tokenizer
+// replaced, kept as the reference the tokenizer must agree with.
+func parseEventLineRegexp(raw string, pos SourcePos) (Event, *Diagnostic, bool) {
+	line := strings.TrimSpace(raw)
+	if line == "" {
+		return Event{}, nil, false
+	}
+	originalLine := line
+	offset := strings.Index(raw, line)
+
+	stripped := referenceSourcePrefixRegex.ReplaceAllString(line, "")
+	offset += len(line) - len(stripped)
+	line = stripped
+
+	diagnose := func(kind DiagnosticKind, group int, loc []int, format string, args ...any) (Event, *Diagnostic, bool) {
+		column := offset + 1
+		if loc != nil && loc[2*group] >= 0 {
+			column += loc[2*group]
+		}
+		return Event{}, &Diagnostic{
+			File:     pos.File,
+			Line:     pos.Line,
+			Column:   column,
+			Offset:   pos.Offset + int64(column-1),
+			Raw:      raw,
+			Kind:     kind,
+			Severity: SeverityError,
+			Message:  fmt.Sprintf(format, args...),
+		}, false
+	}
+
+	loc := referenceEventRegex.FindStringSubmatchIndex(line)
+	if loc == nil {
+		return diagnose(DiagnosticMalformedLine, 0, nil, "expected '[HH:MM:SS.sss] <eventID> <competitorID> [params]'")
+	}
+	group := func(i int) string {
+		if loc[2*i] < 0 {
+			return ""
+		}
+		return line[loc[2*i]:loc[2*i+1]]
+	}
+
+	timestamp, err := ParseTime(group(1))
+	if err != nil {
+		return diagnose(DiagnosticInvalidTimestamp, 1, loc, "invalid timestamp: %v", err)
+	}
+	eventIDInt, err := strconv.Atoi(group(2))
+	if err != nil {
+		return diagnose(DiagnosticInvalidEventID, 2, loc, "invalid event ID: %v", err)
+	}
+	competitorID, err := strconv.Atoi(group(3))
+	if err != nil {
+		return diagnose(DiagnosticInvalidCompetitorID, 3, loc, "invalid competitor ID: %v", err)
+	}
+
+	event := Event{
+		Timestamp:      timestamp,
+		ID:             EventID(eventIDInt),
+		CompetitorID:   competitorID,
+		ExtraParamsStr: strings.TrimSpace(group(4)),
+		Line:           originalLine,
+		Source:         pos,
+	}
+	if err := parseEventParams(&event); err != nil {
+		return diagnose(DiagnosticInvalidParams, 4, loc, "%v", err)
+	}
+	return event, nil, true
+}
+
+// parseShotParamsFields is the strings.Fields based shot parser kept as the
+// reference for parseShotParams.
+func parseShotParamsFields(params string) (int, ShotOutcome, error) {
+	fields := strings.Fields(params)
+	if len(fields) != 2 {
+		return 0, ShotUnknown, fmt.Errorf("expected '<target> <hit|miss>', got '%s'", params)
+	}
+	target, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, ShotUnknown, fmt.Errorf("invalid target '%s': %w", fields[0], err)
+	}
+	switch strings.ToLower(fields[1]) {
+	case "hit", "x":
+		return target, ShotHit, nil
+	case "miss", "0":
+		return target, ShotMiss, nil
+	default:
+		return 0, ShotUnknown, fmt.Errorf("invalid shot result '%s', expected hit or miss", fields[1])
+	}
+}
+
+var tokenizerSeeds = []string{
+	"[09:05:59.867] 1 1",
+	"[09:15:00.841] 2 1 09:30:00.000",
+	"[09:49:31.659] 5 1 1",
+	"[09:49:33.123] 6 1 1",
+	"[09:59:03.872] 11 1 Lost in the forest",
+	"[10:00:00.000] 13 2 3 hit",
+	"[10:00:00.000] 13 2 3\tMISS",
+	"[10:00:00.000] 13 2 3 hit extra",
+	"[10:00:00.000] 15 2 Unsafe rifle handling",
+	"[10:00:00.000] 16 2 30s Short cut",
+	"[10:00:00.000] 16 2 soon",
+	"[10:00:00.000] 17 0 pulse-1",
+	"[10:00:00.000] 18 3 4",
+	"\\s[10:00:00.000] 4 1",
+	"  [10:00:00.000]\t4   1  ",
+	"[10:00:00.000] 4 1\nmore",
+	"[10:00:00.000] 4 1 \n more",
+	"[25:00:00.000] 4 1",
+	"[10:61:00.000] 4 1",
+	"[10:00:00.000]4 1",
+	"[10:00:00.000] 4 1x",
+	"[10:00:00.000] 99999999999999999999 1",
+	"[10:00:00.000] 2 1 later",
+	"[10:00:00] 4 1",
+	"malformed",
+	"",
+}
+
+func TestParseEventLine_MatchesRegexp(t *testing.T) {
+	lines := append([]string(nil), tokenizerSeeds...)
+	data, err := os.ReadFile(filepath.Join("input", "events"))
+	if err != nil {
+		t.Fatalf("Failed to read sample events: %v", err)
+	}
+	lines = append(lines, strings.Split(string(data), "\n")...)
+
+	for i, line := range lines {
+		pos := SourcePos{File: "events", Line: i + 1, Offset: int64(i * 40)}
+		gotEvent, gotDiagnostic, gotOK := ParseEventLine(line, pos)
+		wantEvent, wantDiagnostic, wantOK := parseEventLineRegexp(line, pos)
+		if gotOK != wantOK || !reflect.DeepEqual(gotEvent, wantEvent) || !reflect.DeepEqual(gotDiagnostic, wantDiagnostic) {
+			t.Errorf("ParseEventLine(%q) = %+v, %v, %v; want %+v, %v, %v", line, gotEvent, gotDiagnostic, gotOK, wantEvent, wantDiagnostic, wantOK)
+		}
+	}
+}
+
+func TestParseEventLine_DoesNotAllocate(t *testing.T) {
+	lines := []string{
+		"[09:15:00.841] 2 1 09:30:00.000",
+		"[09:49:31.659] 5 1 1",
+		"[09:59:03.872] 11 1 Lost in the forest",
+		"[10:00:00.000] 13 2 3 hit",
+		"[10:00:00.000] 16 2 30s Short cut",
+	}
+	for _, line := range lines {
+		allocs := testing.AllocsPerRun(100, func() {
+			ParseEventLine(line, SourcePos{Line: 1})
+		})
+		if allocs != 0 {
+			t.Errorf("ParseEventLine(%q) made %v allocations, want 0", line, allocs)
+		}
+	}
+}
+
+func FuzzParseEventLine(f *testing.F) {
+	for _, seed := range tokenizerSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		pos := SourcePos{File: "events", Line: 3, Offset: 120}
+		gotEvent, gotDiagnostic, gotOK := ParseEventLine(line, pos)
+		wantEvent, wantDiagnostic, wantOK := parseEventLineRegexp(line, pos)
+		if gotOK != wantOK || !reflect.DeepEqual(gotEvent, wantEvent) || !reflect.DeepEqual(gotDiagnostic, wantDiagnostic) {
+			t.Fatalf("ParseEventLine(%q) = %+v, %v, %v; want %+v, %v, %v", line, gotEvent, gotDiagnostic, gotOK, wantEvent, wantDiagnostic, wantOK)
+		}
+	})
+}
+
+func FuzzParseShotParams(f *testing.F) {
+	for _, seed := range []string{"3 hit", "3 MISS", "1\tx", "2 0", "3", "3 hit extra", " 4  miss ", "a hit"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, params string) {
+		target, shot, err := parseShotParams(params)
+		wantTarget, wantShot, wantErr := parseShotParamsFields(params)
+		if target != wantTarget || shot != wantShot || fmt.Sprint(err) != fmt.Sprint(wantErr) {
+			t.Fatalf("parseShotParams(%q) = %d, %v, %v; want %d, %v, %v", params, target, shot, err, wantTarget, wantShot, wantErr)
+		}
+	})
+}
+
+func benchmarkLines(b *testing.B) []string {
+	b.Helper()
+	var buf bytes.Buffer
+	if err := WriteEvents(&buf, GenerateRace(createTestConfig(), 50, DefaultRaceProfile(), 1)); err != nil {
+		b.Fatalf("WriteEvents() error = %v", err)
+	}
+	var lines []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func BenchmarkParseEventLine(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		parse func(string, SourcePos) (Event, *Diagnostic, bool)
+	}{
+		{name: "Tokenizer", parse: ParseEventLine},
+		{name: "Regexp", parse: parseEventLineRegexp},
+	}
+	lines := benchmarkLines(b)
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				line := lines[i%len(lines)]
+				if _, _, ok := bm.parse(line, SourcePos{Line: i}); !ok {
+					b.Fatalf("Failed to parse %q", line)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLoadEvents(b *testing.B) {
+	lines := benchmarkLines(b)
+	path := filepath.Join(b.TempDir(), "events")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		b.Fatalf("Failed to write events: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := LoadEvents(path); err != nil {
+			b.Fatalf("LoadEvents() error = %v", err)
+		}
+	}
+}